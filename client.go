@@ -1,10 +1,9 @@
 package httpclient
 
 import (
-	"errors"
-	"net"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"golang.org/x/net/proxy"
@@ -12,17 +11,30 @@ import (
 
 // ClientSetting http client configure
 type ClientSetting struct {
-	UserAgent      string
-	Proxy          int
-	Proto          string
-	ProtoMajor     int
-	ProtoMinor     int
-	Timeout        time.Duration
-	Retries        int // if set to -1 means will retry forever
-	proxyType      ProxyType
-	proxyURL       string
-	ProxyTransport *http.Transport
-	err            error
+	UserAgent       string
+	Proxy           int
+	Proto           string
+	ProtoMajor      int
+	ProtoMinor      int
+	Timeout         time.Duration // default total timeout of the requests, 0 means no limit
+	Retries         int           // if set to -1 means will retry until the context is done, with backoff
+	proxyType       ProxyType
+	proxyURL        string
+	ProxyTransport  *http.Transport
+	base            *http.Transport // ProxyTransport before install configured it
+	protocol        Protocol
+	dialer          Dialer
+	pool            PoolOptions
+	timeouts        Timeouts
+	redirect        *RedirectPolicy
+	idempotencyKeys func() string
+	compression     *Compression
+	validators      *ValidatorStore
+	backoff         *RetryBackoff
+	metrics         Metrics
+	tracing         *tracing
+	logging         *logging
+	err             error
 }
 
 // ProxyType 代理类型
@@ -37,22 +49,24 @@ const (
 
 var (
 	// DefaultSetting default configure
-	setting *ClientSetting
+	setting     *ClientSetting
+	settingOnce sync.Once
 )
 
 // Settings 获取设置项
 func Settings() *ClientSetting {
-
-	if setting == nil {
+	settingOnce.Do(func() {
 		setting = &ClientSetting{
 			UserAgent:  "lets-go-go httpclient",
-			Proto:      "HTTP/1.1",
-			ProtoMajor: 1,
-			ProtoMinor: 1,
+			Proto:      "HTTP/2.0",
+			ProtoMajor: 2,
+			ProtoMinor: 0,
+			protocol:   ProtoHTTP2,
 			proxyType:  NoProxy,
-			Timeout:    3 * time.Second,
+			pool:       DefaultPoolOptions,
 		}
-	}
+		setting.install(http.DefaultTransport.(*http.Transport).Clone())
+	})
 
 	return setting
 }
@@ -63,43 +77,40 @@ func (c *ClientSetting) SetUserAgent(userAgent string) *ClientSetting {
 	return c
 }
 
-// SetProto sets the useragent of the request.
+// SetProto selects the protocol by its version: "HTTP/1.0" or "HTTP/1.1"
+// for ProtoHTTP1, "HTTP/2" or "HTTP/2.0" for ProtoHTTP2 and "h2c" for
+// ProtoH2C. See SetProtocol.
 func (c *ClientSetting) SetProto(proto string) *ClientSetting {
-	protoMajor, protoMinor, ok := http.ParseHTTPVersion(c.Proto)
-	if ok {
-		c.Proto = proto
-		c.ProtoMajor, c.ProtoMinor = protoMajor, protoMinor
-
-		c.err = errors.New("invalid PROTOCOL version")
+	p, major, minor, err := parseProto(proto)
+	if err != nil {
+		c.err = err
+		return c
 	}
 
+	c.SetProtocol(p)
+	c.Proto, c.ProtoMajor, c.ProtoMinor = proto, major, minor
 	return c
 }
 
 // SetProxy sets the address of the proxy which used by the request.
 func (c *ClientSetting) SetProxy(proxyType ProxyType, addr string) *ClientSetting {
+	c.proxyType, c.proxyURL = proxyType, addr
 
 	if proxyType == NoProxy {
-		c.ProxyTransport = &http.Transport{
-			Proxy: nil,
-		}
+		c.install(&http.Transport{
+			Proxy:               nil,
+			TLSHandshakeTimeout: 10 * time.Second,
+		})
 		return c
 	}
 
 	// 系统默认代理
 	if proxyType == DefaultProxy {
-		c.ProxyTransport = &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-				DualStack: true,
-			}).DialContext,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
+		c.install(&http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
 			TLSHandshakeTimeout:   10 * time.Second,
 			ExpectContinueTimeout: 1 * time.Second,
-		}
+		})
 		return c
 	}
 
@@ -113,27 +124,28 @@ func (c *ClientSetting) SetProxy(proxyType ProxyType, addr string) *ClientSettin
 
 	switch u.Scheme {
 	case "http", "https":
-		c.ProxyTransport = &http.Transport{
-			Proxy: http.ProxyURL(u),
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
+		c.install(&http.Transport{
+			Proxy:               http.ProxyURL(u),
 			TLSHandshakeTimeout: 10 * time.Second,
+		})
+	case "socks5", "socks5h":
+		var forward proxy.Dialer = proxy.Direct
+		if c.dialer != nil {
+			forward = proxyDialer{c.dialer}
 		}
-	case "socks5":
-		dialer, err := proxy.FromURL(u, proxy.Direct)
+
+		dialer, err := socksDialer(u, forward)
 
 		if err != nil {
 			c.err = err
 			return c
 		}
 
-		c.ProxyTransport = &http.Transport{
+		c.install(&http.Transport{
 			Proxy:               http.ProxyFromEnvironment,
 			Dial:                dialer.Dial,
 			TLSHandshakeTimeout: 10 * time.Second,
-		}
+		})
 	}
 
 	return c
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+var clientTemplate = template.Must(template.New("client").Funcs(template.FuncMap{
+	"quote":    strconv.Quote,
+	"unexport": unexport,
+	"params":   formatParams,
+	"results":  formatResults,
+	"bind":     bindParams,
+}).Parse(`// Code generated by httpclient-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .UsesFmt}}
+	"fmt"
+
+{{end}}
+	"github.com/lets-go-go/httpclient"
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+
+type {{unexport .Name}}Client struct {
+	session *httpclient.Session
+}
+
+// New{{.Name}} returns a {{.Name}} which sends its requests through session.
+func New{{.Name}}(session *httpclient.Session) {{.Name}} {
+	return &{{unexport .Name}}Client{session: session}
+}
+{{- range .ErrorTypes}}
+
+var _ error = (*{{.}})(nil)
+{{- end}}
+{{range .Methods}}
+func (c *{{unexport $.Name}}Client) {{.Name}}({{params .}}) {{results .}} {
+	req := c.session.To({{quote .Method}}, {{quote .Path}})
+{{bind .}}
+{{- if .Raw}}
+	return req.Execute()
+{{- else if .Result}}
+	var out {{.Result}}
+	err := req.Decode(&out, {{if .Error}}new({{.Error}}){{else}}nil{{end}})
+	return out, err
+{{- else}}
+	return req.Decode(nil, {{if .Error}}new({{.Error}}){{else}}nil{{end}})
+{{- end}}
+}
+{{end}}`))
+
+func generate(a *api) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := clientTemplate.Execute(&buf, a); err != nil {
+		return nil, err
+	}
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+
+	return code, nil
+}
+
+func unexport(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+func formatParams(m *method) string {
+	s := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		s[i] = p.Name + " " + p.Type
+	}
+	return strings.Join(s, ", ")
+}
+
+func formatResults(m *method) string {
+	switch {
+	case m.Raw:
+		return "(*httpclient.Response, error)"
+	case m.Result != "":
+		return "(" + m.Result + ", error)"
+	}
+	return "error"
+}
+
+func bindParams(m *method) string {
+	var b strings.Builder
+
+	for _, v := range templateVars(m.Path) {
+		if p := lookupParam(m.Params, v); p != nil {
+			bindPath(&b, v, p)
+		}
+	}
+
+	for _, h := range m.Headers {
+		if h.Param != "" {
+			fmt.Fprintf(&b, "\treq.SetHeader(%q, fmt.Sprint(%s))\n", h.Name, h.Param)
+		} else {
+			fmt.Fprintf(&b, "\treq.SetHeader(%q, %q)\n", h.Name, h.Value)
+		}
+	}
+
+	bindValues(&b, m, m.Queries, "QueryStruct", "AddQuery")
+	bindValues(&b, m, m.Forms, "FormStruct", "AddField")
+	if len(m.Forms) > 0 {
+		// AddField leaves the Content-Type alone
+		b.WriteString("\treq.SetContentType(\"urlencoded\")\n")
+	}
+
+	if m.Body != "" {
+		fmt.Fprintf(&b, "\treq.SendBody(%s)\n", m.Body)
+	}
+
+	return b.String()
+}
+
+// bindPath binds the parameter p to the template variable v. checkParams
+// has already rejected the types PathParam can't expand.
+func bindPath(b *strings.Builder, v string, p *param) {
+	switch kindOf(p.Type) {
+	case kindPointer:
+		fmt.Fprintf(b, "\tif %s != nil {\n\t\treq.PathParam(%q, *%s)\n\t}\n", p.Name, v, p.Name)
+	case kindSlice:
+		if elemType(p.Type) == "string" {
+			fmt.Fprintf(b, "\treq.PathParam(%q, %s)\n", v, p.Name)
+			return
+		}
+		fmt.Fprintf(b, "\t%sValues := make([]string, len(%s))\n", p.Name, p.Name)
+		fmt.Fprintf(b, "\tfor i, v := range %s {\n\t\t%sValues[i] = fmt.Sprint(v)\n\t}\n", p.Name, p.Name)
+		fmt.Fprintf(b, "\treq.PathParam(%q, %sValues)\n", v, p.Name)
+	case kindBytes:
+		fmt.Fprintf(b, "\treq.PathParam(%q, string(%s))\n", v, p.Name)
+	default:
+		fmt.Fprintf(b, "\treq.PathParam(%q, %s)\n", v, p.Name)
+	}
+}
+
+func bindValues(b *strings.Builder, m *method, bindings []binding, structFn, valueFn string) {
+	for _, v := range bindings {
+		kind := kindOf(m.paramType(v.Param))
+		if kind == kindStruct && v.Key == "" {
+			fmt.Fprintf(b, "\treq.%s(%s)\n", structFn, v.Param)
+			continue
+		}
+
+		key := v.Key
+		if key == "" {
+			key = snakeCase(v.Param)
+		}
+
+		switch kind {
+		case kindPointer:
+			fmt.Fprintf(b, "\tif %s != nil {\n\t\treq.%s(%q, fmt.Sprint(*%s))\n\t}\n", v.Param, valueFn, key, v.Param)
+		case kindSlice:
+			fmt.Fprintf(b, "\tfor _, v := range %s {\n\t\treq.%s(%q, fmt.Sprint(v))\n\t}\n", v.Param, valueFn, key)
+		case kindBytes:
+			fmt.Fprintf(b, "\treq.%s(%q, string(%s))\n", valueFn, key, v.Param)
+		default:
+			fmt.Fprintf(b, "\treq.%s(%q, fmt.Sprint(%s))\n", valueFn, key, v.Param)
+		}
+	}
+}
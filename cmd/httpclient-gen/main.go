@@ -0,0 +1,109 @@
+// Command httpclient-gen generates typed HTTP clients from annotated Go
+// interfaces. The generated code is built on httpclient.Session.
+//
+// Methods of the interface are annotated in their doc comments:
+//
+//	// GitHub is the GitHub REST API.
+//	//
+//	// @Header Accept: application/vnd.github+json
+//	// @Error APIError
+//	type GitHub interface {
+//		// ListRepos lists the public repositories of a user.
+//		//
+//		// @GET /users/{user}/repos{?page,per_page}
+//		ListRepos(user string, page, perPage int) ([]Repo, error)
+//
+//		// CreateRepo creates a repository for the authenticated user.
+//		//
+//		// @POST /user/repos
+//		// @Header Authorization: {token}
+//		// @Body repo
+//		CreateRepo(token string, repo NewRepo) (*Repo, error)
+//	}
+//
+// The supported annotations are:
+//
+//	@GET, @POST, @PUT, @PATCH, @DELETE, @HEAD, @OPTIONS <URI template>
+//	@Header Name: value     value may be a {param} reference
+//	@Query param [name]     struct params use QueryStruct, others AddQuery
+//	@Form param [name]      struct params use FormStruct, others AddField,
+//	                        sent as application/x-www-form-urlencoded
+//	@Body param             sent as JSON with SendBody
+//	@Error Type             error responses (status >= 400) are decoded into
+//	                        *Type, which must implement error
+//
+// @Header and @Error may also be given on the interface, they apply to every
+// method. Parameters used by the URI template are bound with PathParam, the
+// template variable is matched against the parameter name or its snake_case
+// form. Path parameters are basic types, pointers or slices of them, []byte
+// or map[string]string. Every parameter must be used. Slice parameters of
+// @Query and @Form add one value per element, and nil pointers are omitted.
+//
+// Methods return error, (T, error) to decode the JSON body into T, or
+// (*httpclient.Response, error) to get the raw response.
+//
+// Usage:
+//
+//	//go:generate go run github.com/lets-go-go/httpclient/cmd/httpclient-gen -type GitHub
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeName = flag.String("type", "", "name of the interface to generate a client for; required")
+	output   = flag.String("output", "", "output file name; default <src>_client.go")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: httpclient-gen -type T [-output file] [file.go]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src := os.Getenv("GOFILE")
+	if flag.NArg() > 0 {
+		src = flag.Arg(0)
+	}
+
+	if src == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(src, *typeName, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "httpclient-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(src, name, out string) error {
+	api, err := parseFile(src, name)
+	if err != nil {
+		return err
+	}
+
+	code, err := generate(api)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		base := strings.TrimSuffix(filepath.Base(src), ".go")
+		out = filepath.Join(filepath.Dir(src), base+"_client.go")
+	}
+
+	return ioutil.WriteFile(out, code, 0644)
+}
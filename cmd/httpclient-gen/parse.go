@@ -0,0 +1,583 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// api is the parsed form of an annotated interface.
+type api struct {
+	Package string
+	Name    string
+	Imports []string
+	Methods []*method
+	UsesFmt bool
+
+	ErrorTypes []string // @Error types, asserted to implement error
+}
+
+type method struct {
+	Name    string
+	Params  []*param
+	Method  string
+	Path    string
+	Headers []header
+	Queries []binding
+	Forms   []binding
+	Body    string
+	Error   string
+	Result  string // type of the decoded result, empty when only error is returned
+	Raw     bool   // returns (*httpclient.Response, error)
+}
+
+// usesFmt reports whether the generated method formats parameters with
+// fmt.Sprint.
+func (m *method) usesFmt() bool {
+	for _, h := range m.Headers {
+		if h.Param != "" {
+			return true
+		}
+	}
+
+	for _, v := range templateVars(m.Path) {
+		if p := lookupParam(m.Params, v); p != nil && kindOf(p.Type) == kindSlice && elemType(p.Type) != "string" {
+			return true
+		}
+	}
+
+	for _, b := range append(append([]binding(nil), m.Queries...), m.Forms...) {
+		switch kindOf(m.paramType(b.Param)) {
+		case kindBytes:
+		case kindStruct:
+			if b.Key != "" {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *method) paramType(name string) string {
+	for _, p := range m.Params {
+		if p.Name == name {
+			return p.Type
+		}
+	}
+	return ""
+}
+
+type param struct {
+	Name string
+	Type string
+}
+
+type header struct {
+	Name  string
+	Value string // literal value
+	Param string // parameter reference
+}
+
+// binding binds a parameter to a query or form field. Key is empty for
+// struct parameters.
+type binding struct {
+	Param string
+	Key   string
+}
+
+var httpMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "HEAD": true, "OPTIONS": true,
+}
+
+var basicTypes = map[string]bool{
+	"string": true, "bool": true, "byte": true, "rune": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+// Kinds of the parameters bound to path, query or form values.
+const (
+	kindBasic   = iota // added with fmt.Sprint
+	kindPointer        // pointer to a basic type, added when not nil
+	kindSlice          // slice of a basic type, one value per element
+	kindBytes          // []byte, added as a string
+	kindStruct         // encoded with QueryStruct or FormStruct
+)
+
+// kindOf returns the kind of a parameter of type typ. Types which are not
+// basic types, pointers or slices of them are assumed to be structs.
+func kindOf(typ string) int {
+	switch {
+	case basicTypes[typ]:
+		return kindBasic
+	case typ == "[]byte" || typ == "[]uint8":
+		return kindBytes
+	case strings.HasPrefix(typ, "*") && basicTypes[typ[1:]]:
+		return kindPointer
+	case strings.HasPrefix(typ, "[]") && basicTypes[typ[2:]]:
+		return kindSlice
+	case strings.HasPrefix(typ, "...") && basicTypes[typ[3:]]:
+		return kindSlice
+	}
+	return kindStruct
+}
+
+// elemType returns the element type of a slice or variadic type.
+func elemType(typ string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(typ, "..."), "[]")
+}
+
+func parseFile(filename, name string) (*api, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		iface *ast.InterfaceType
+		doc   *ast.CommentGroup
+	)
+
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if ts.Name.Name != name {
+				continue
+			}
+
+			if iface, ok = ts.Type.(*ast.InterfaceType); !ok {
+				return nil, fmt.Errorf("%s is not an interface", name)
+			}
+
+			doc = ts.Doc
+			if doc == nil {
+				doc = gd.Doc
+			}
+		}
+	}
+
+	if iface == nil {
+		return nil, fmt.Errorf("interface %s not found in %s", name, filename)
+	}
+
+	a := &api{Package: f.Name.Name, Name: name}
+
+	var defaults method
+	if err := parseAnnotations(&defaults, doc, true); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	used := make(map[string]bool)
+
+	for _, field := range iface.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded interfaces are not supported", name)
+		}
+
+		m := &method{
+			Name:    field.Names[0].Name,
+			Headers: append([]header(nil), defaults.Headers...),
+			Error:   defaults.Error,
+		}
+
+		if err := parseAnnotations(m, field.Doc, false); err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", name, m.Name, err)
+		}
+
+		if err := parseSignature(fset, m, ft); err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", name, m.Name, err)
+		}
+
+		collectPackages(ft, used)
+		a.UsesFmt = a.UsesFmt || m.usesFmt()
+		a.Methods = append(a.Methods, m)
+
+		if m.Error != "" && !contains(a.ErrorTypes, m.Error) {
+			a.ErrorTypes = append(a.ErrorTypes, m.Error)
+			if i := strings.IndexByte(m.Error, '.'); i > 0 {
+				used[m.Error[:i]] = true
+			}
+		}
+	}
+
+	if err := checkErrorTypes(filepath.Dir(filename), a.ErrorTypes); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	for _, spec := range f.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		local := path.Base(p)
+		if spec.Name != nil {
+			local = spec.Name.Name
+		}
+
+		if used[local] && p != "github.com/lets-go-go/httpclient" {
+			if spec.Name != nil {
+				a.Imports = append(a.Imports, local+" "+strconv.Quote(p))
+			} else {
+				a.Imports = append(a.Imports, strconv.Quote(p))
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// checkErrorTypes verifies that the @Error types declared in the package
+// in dir implement error, Response.Decode drops the body of the ones which
+// don't. Types of other packages are checked by the compiler through the
+// assertions of the generated code.
+func checkErrorTypes(dir string, types []string) error {
+	if len(types) == 0 {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return err
+	}
+
+	declared := make(map[string]bool)
+	errorMethod := make(map[string]bool)
+
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				switch d := decl.(type) {
+				case *ast.GenDecl:
+					for _, spec := range d.Specs {
+						if ts, ok := spec.(*ast.TypeSpec); ok {
+							declared[ts.Name.Name] = true
+						}
+					}
+				case *ast.FuncDecl:
+					if isErrorMethod(fset, d) {
+						recv := d.Recv.List[0].Type
+						if star, ok := recv.(*ast.StarExpr); ok {
+							recv = star.X
+						}
+						if id, ok := recv.(*ast.Ident); ok {
+							errorMethod[id.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, t := range types {
+		if declared[t] && !errorMethod[t] {
+			return fmt.Errorf("@Error type %s does not implement error, it needs an Error() string method", t)
+		}
+	}
+
+	return nil
+}
+
+func isErrorMethod(fset *token.FileSet, d *ast.FuncDecl) bool {
+	if d.Recv == nil || len(d.Recv.List) != 1 || d.Name.Name != "Error" {
+		return false
+	}
+
+	ft := d.Type
+	if ft.Params.NumFields() != 0 || ft.Results.NumFields() != 1 {
+		return false
+	}
+
+	return exprString(fset, ft.Results.List[0].Type) == "string"
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAnnotations(m *method, doc *ast.CommentGroup, iface bool) error {
+	if doc == nil {
+		return nil
+	}
+
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "@") {
+			continue
+		}
+
+		fields := strings.Fields(line[1:])
+		if len(fields) == 0 {
+			return fmt.Errorf("empty annotation")
+		}
+
+		key, args := fields[0], fields[1:]
+
+		if httpMethods[key] {
+			if iface {
+				return fmt.Errorf("@%s is only allowed on methods", key)
+			}
+			if len(args) != 1 {
+				return fmt.Errorf("@%s expects a URI template", key)
+			}
+			m.Method, m.Path = key, args[0]
+			continue
+		}
+
+		switch key {
+		case "Header":
+			rest := strings.TrimSpace(strings.TrimPrefix(line, "@Header"))
+			i := strings.IndexByte(rest, ':')
+			if i <= 0 {
+				return fmt.Errorf("@Header expects \"Name: value\"")
+			}
+
+			h := header{Name: strings.TrimSpace(rest[:i]), Value: strings.TrimSpace(rest[i+1:])}
+			if strings.HasPrefix(h.Value, "{") && strings.HasSuffix(h.Value, "}") {
+				if iface {
+					return fmt.Errorf("@Header parameter references are only allowed on methods")
+				}
+				h.Param, h.Value = h.Value[1:len(h.Value)-1], ""
+			}
+			m.Headers = append(m.Headers, h)
+		case "Query", "Form":
+			if iface {
+				return fmt.Errorf("@%s is only allowed on methods", key)
+			}
+			if len(args) < 1 || len(args) > 2 {
+				return fmt.Errorf("@%s expects a parameter and an optional name", key)
+			}
+
+			b := binding{Param: args[0]}
+			if len(args) == 2 {
+				b.Key = args[1]
+			}
+
+			if key == "Query" {
+				m.Queries = append(m.Queries, b)
+			} else {
+				m.Forms = append(m.Forms, b)
+			}
+		case "Body":
+			if iface {
+				return fmt.Errorf("@Body is only allowed on methods")
+			}
+			if len(args) != 1 {
+				return fmt.Errorf("@Body expects a parameter")
+			}
+			m.Body = args[0]
+		case "Error":
+			if len(args) != 1 {
+				return fmt.Errorf("@Error expects a type")
+			}
+			m.Error = args[0]
+		default:
+			return fmt.Errorf("unknown annotation @%s", key)
+		}
+	}
+
+	if !iface && m.Method == "" {
+		return fmt.Errorf("missing HTTP method annotation such as @GET")
+	}
+
+	return nil
+}
+
+func parseSignature(fset *token.FileSet, m *method, ft *ast.FuncType) error {
+	for _, field := range ft.Params.List {
+		typ := exprString(fset, field.Type)
+
+		if len(field.Names) == 0 {
+			return fmt.Errorf("parameters must be named")
+		}
+
+		for _, n := range field.Names {
+			m.Params = append(m.Params, &param{Name: n.Name, Type: typ})
+		}
+	}
+
+	var results []string
+	if ft.Results != nil {
+		for _, field := range ft.Results.List {
+			typ := exprString(fset, field.Type)
+			n := len(field.Names)
+			if n == 0 {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				results = append(results, typ)
+			}
+		}
+	}
+
+	switch {
+	case len(results) == 1 && results[0] == "error":
+	case len(results) == 2 && results[1] == "error":
+		if results[0] == "*httpclient.Response" {
+			m.Raw = true
+		} else {
+			m.Result = results[0]
+		}
+	default:
+		return fmt.Errorf("methods must return error or (T, error)")
+	}
+
+	return checkParams(m)
+}
+
+// checkParams verifies that every parameter is used and every
+// reference names a parameter.
+func checkParams(m *method) error {
+	params := make(map[string]*param)
+	for _, p := range m.Params {
+		params[p.Name] = p
+	}
+
+	used := make(map[string]bool)
+	use := func(name, what string) error {
+		if params[name] == nil {
+			return fmt.Errorf("%s references unknown parameter %q", what, name)
+		}
+		used[name] = true
+		return nil
+	}
+
+	for _, v := range templateVars(m.Path) {
+		if p := lookupParam(m.Params, v); p != nil {
+			if kindOf(p.Type) == kindStruct && p.Type != "map[string]string" {
+				return fmt.Errorf("path parameter %q has unsupported type %s", p.Name, p.Type)
+			}
+			used[p.Name] = true
+		}
+	}
+
+	for _, h := range m.Headers {
+		if h.Param != "" {
+			if err := use(h.Param, "@Header"); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, b := range m.Queries {
+		if err := use(b.Param, "@Query"); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range m.Forms {
+		if err := use(b.Param, "@Form"); err != nil {
+			return err
+		}
+	}
+
+	if m.Body != "" {
+		if err := use(m.Body, "@Body"); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range m.Params {
+		if !used[p.Name] {
+			return fmt.Errorf("parameter %q is not used by any annotation", p.Name)
+		}
+	}
+
+	return nil
+}
+
+// templateVars returns the variable names of an RFC 6570 URI template.
+func templateVars(tmpl string) []string {
+	var vars []string
+
+	for {
+		i := strings.IndexByte(tmpl, '{')
+		if i < 0 {
+			return vars
+		}
+
+		j := strings.IndexByte(tmpl[i:], '}')
+		if j < 0 {
+			return vars
+		}
+
+		expr := strings.TrimLeft(tmpl[i+1:i+j], "+#./;?&")
+		for _, spec := range strings.Split(expr, ",") {
+			if k := strings.IndexAny(spec, ":*"); k >= 0 {
+				spec = spec[:k]
+			}
+			vars = append(vars, spec)
+		}
+
+		tmpl = tmpl[i+j+1:]
+	}
+}
+
+// lookupParam finds the parameter bound to the template variable v, which
+// is either the parameter name or its snake_case form.
+func lookupParam(params []*param, v string) *param {
+	for _, p := range params {
+		if p.Name == v || snakeCase(p.Name) == v {
+			return p
+		}
+	}
+	return nil
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func collectPackages(n ast.Node, used map[string]bool) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+}
+
+func exprString(fset *token.FileSet, expr ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, expr)
+	return buf.String()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/lets-go-go/httpclient"
+)
+
+// saveBody writes the response body to file, derived from the response when
+// empty, and prints the progress on stderr.
+func saveBody(res *httpclient.Response, file string) error {
+	defer res.Body.Close()
+
+	if !res.OK() {
+		return fmt.Errorf("download failed: %s", res.Status)
+	}
+
+	if file == "" {
+		file = downloadName(res)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	p := &progress{total: res.ContentLength, start: time.Now(), name: file}
+	_, err = io.Copy(f, io.TeeReader(res.Body, p))
+	p.print(true)
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func downloadName(res *httpclient.Response) string {
+	if _, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil {
+		if name := path.Base(params["filename"]); params["filename"] != "" && name != "/" && name != "." {
+			return name
+		}
+	}
+
+	name := path.Base(res.Request.URL.Path)
+	if name == "/" || name == "." {
+		name = "index"
+	}
+
+	if !strings.Contains(name, ".") {
+		if exts, _ := mime.ExtensionsByType(res.ContentType()); len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+
+	return name
+}
+
+// progress prints the download progress at most every 100ms.
+type progress struct {
+	name    string
+	total   int64
+	written int64
+	start   time.Time
+	last    time.Time
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	p.print(false)
+	return len(b), nil
+}
+
+func (p *progress) print(done bool) {
+	now := time.Now()
+	if !done && now.Sub(p.last) < 100*time.Millisecond {
+		return
+	}
+	p.last = now
+
+	elapsed := now.Sub(p.start).Seconds()
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.written) / elapsed
+	}
+
+	if p.total > 0 {
+		fmt.Fprintf(os.Stderr, "\r%s %3d%% %s/%s %s/s ", p.name, p.written*100/p.total, size(p.written), size(p.total), size(int64(rate)))
+	} else {
+		fmt.Fprintf(os.Stderr, "\r%s %s %s/s ", p.name, size(p.written), size(int64(rate)))
+	}
+
+	if done {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func size(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
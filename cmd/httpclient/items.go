@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+type itemKind int
+
+const (
+	headerItem itemKind = iota
+	queryItem
+	dataItem
+	jsonItem
+	fileItem
+	bodyItem
+)
+
+type item struct {
+	kind  itemKind
+	key   string
+	value string
+	raw   json.RawMessage // jsonItem
+}
+
+// separators in the order they are tried when they start at the same
+// position, longest first.
+var separators = []struct {
+	sep  string
+	kind itemKind
+	file bool
+}{
+	{":=@", jsonItem, true},
+	{"==", queryItem, false},
+	{"=@", dataItem, true},
+	{":=", jsonItem, false},
+	{"=", dataItem, false},
+	{":", headerItem, false},
+	{"@", fileItem, false},
+}
+
+var methodPattern = regexp.MustCompile(`^[A-Z]+$`)
+
+// parseItem parses a request item, the earliest separator wins.
+func parseItem(s string) (item, error) {
+	if strings.HasPrefix(s, "@") {
+		return item{kind: bodyItem, value: s[1:]}, nil
+	}
+
+	pos, match := -1, -1
+	for i, sep := range separators {
+		if p := strings.Index(s, sep.sep); p > 0 && (pos < 0 || p < pos) {
+			pos, match = p, i
+		}
+	}
+
+	if match < 0 {
+		return item{}, fmt.Errorf("invalid request item %q", s)
+	}
+
+	sep := separators[match]
+	it := item{kind: sep.kind, key: s[:pos], value: s[pos+len(sep.sep):]}
+
+	if sep.file {
+		b, err := ioutil.ReadFile(it.value)
+		if err != nil {
+			return item{}, err
+		}
+		it.value = string(b)
+	}
+
+	if it.kind == jsonItem {
+		if !json.Valid([]byte(it.value)) {
+			return item{}, fmt.Errorf("invalid JSON in request item %q", s)
+		}
+		it.raw = json.RawMessage(it.value)
+	}
+
+	return it, nil
+}
+
+// request is the request described by the command-line arguments.
+type request struct {
+	method string
+	url    string
+	items  []item
+}
+
+func parseArgs(args []string) (*request, error) {
+	r := new(request)
+
+	if len(args) > 1 && methodPattern.MatchString(args[0]) {
+		r.method, args = args[0], args[1:]
+	}
+
+	r.url = normalizeURL(args[0])
+
+	hasData := false
+	for _, a := range args[1:] {
+		it, err := parseItem(a)
+		if err != nil {
+			return nil, err
+		}
+
+		switch it.kind {
+		case dataItem, jsonItem, fileItem, bodyItem:
+			hasData = true
+		}
+
+		r.items = append(r.items, it)
+	}
+
+	if r.method == "" {
+		r.method = http.MethodGet
+		if hasData {
+			r.method = http.MethodPost
+		}
+	}
+
+	return r, nil
+}
+
+// header returns the headers given by the header items.
+func (r *request) header() http.Header {
+	h := make(http.Header)
+	for _, it := range r.items {
+		if it.kind == headerItem {
+			h.Set(it.key, it.value)
+		}
+	}
+	return h
+}
+
+func normalizeURL(u string) string {
+	switch {
+	case strings.HasPrefix(u, ":"):
+		return "http://localhost" + u
+	case !strings.Contains(u, "://"):
+		return "http://" + u
+	}
+	return u
+}
@@ -0,0 +1,74 @@
+// Command httpclient is a command-line HTTP client built on the httpclient
+// package, with a syntax close to HTTPie:
+//
+//	httpclient [flags] [METHOD] URL [ITEM ...]
+//
+// The method defaults to GET, or POST when data items are given. A URL
+// without scheme uses http://, and ":3000/path" is short for
+// "http://localhost:3000/path". The items are:
+//
+//	Header:value     request header
+//	name==value      query parameter
+//	name=value       string field of the JSON body, or form field with -form
+//	name:=json       raw JSON field of the JSON body
+//	name=@file       string field read from file
+//	name:=@file      raw JSON field read from file
+//	field@file       file uploaded in a multipart form, requires -form
+//	@file            request body read from file
+//
+// Examples:
+//
+//	httpclient POST api.example.com/users name=bob age:=42 Authorization:"Bearer $TOKEN"
+//	httpclient -form POST :8080/upload title=report file@report.pdf
+//	httpclient -download -o image.iso https://example.com/image.iso
+//	httpclient -session=work api.example.com/me    # headers and cookies are kept across runs
+//
+// Sessions are stored as JSON in <user config dir>/httpclient/sessions/<host>/<name>.json,
+// or in the file given by -session when it contains a path separator. They
+// keep the header items given, except Authorization, the -auth credentials
+// and the cookies received.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+var (
+	form        = flag.Bool("form", false, "send the data items as a form instead of JSON")
+	auth        = flag.String("auth", "", "basic authentication credentials `user:password`")
+	proxyURL    = flag.String("proxy", "", "proxy `URL` (http, https or socks5), or \"system\" to use the environment")
+	timeout     = flag.Duration("timeout", 30*time.Second, "request timeout, 0 means no timeout")
+	retries     = flag.Int("retries", 0, "number of retries when no response is received")
+	sessionName = flag.String("session", "", "persist headers, cookies and auth in the named session")
+	download    = flag.Bool("download", false, "save the response body to a file and show the progress")
+	outputFile  = flag.String("o", "", "output `file` of -download; default derived from the response")
+	verbose     = flag.Bool("v", false, "print the request before the response")
+	headersOnly = flag.Bool("headers", false, "print only the response headers")
+	bodyOnly    = flag.Bool("body", false, "print only the response body")
+	checkStatus = flag.Bool("check-status", false, "exit with 3, 4 or 5 on 3xx, 4xx or 5xx responses")
+	curl        = flag.Bool("curl", false, "print the equivalent curl command instead of sending the request")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: httpclient [flags] [METHOD] URL [ITEM ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	code, err := run(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "httpclient: %v\n", err)
+		os.Exit(1)
+	}
+
+	os.Exit(code)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lets-go-go/httpclient"
+)
+
+func run(args []string) (int, error) {
+	r, err := parseArgs(args)
+	if err != nil {
+		return 0, err
+	}
+
+	switch *proxyURL {
+	case "":
+	case "system":
+		httpclient.Settings().SetProxy(httpclient.DefaultProxy, "")
+	default:
+		httpclient.Settings().SetProxy(httpclient.CustomProxy, *proxyURL)
+	}
+
+	var sess *session
+	if *sessionName != "" {
+		if sess, err = loadSession(*sessionName, r.url); err != nil {
+			return 0, err
+		}
+	}
+
+	c, err := build(r, sess)
+	if err != nil {
+		return 0, err
+	}
+
+	if *curl {
+		cmd, err := c.Curl()
+		if err != nil {
+			return 0, err
+		}
+		fmt.Println(cmd)
+		return 0, nil
+	}
+
+	if *verbose {
+		httpclient.Settings().SetLogger(requestPrinter{os.Stdout}, httpclient.LogOptions{
+			Level:       httpclient.LogBodies,
+			MaxBodySize: 1 << 20,
+		})
+	}
+
+	res, err := c.Execute()
+	if err != nil {
+		return 0, err
+	}
+
+	if sess != nil {
+		sess.update(r.header(), res.Cookies())
+		if err := sess.save(); err != nil {
+			return 0, err
+		}
+	}
+
+	if *download {
+		err = saveBody(res, *outputFile)
+	} else {
+		err = printResponse(os.Stdout, res)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if *checkStatus && res.StatusCode >= 300 {
+		return res.StatusCode / 100, nil
+	}
+
+	return 0, nil
+}
+
+// build creates the client sending the request.
+func build(r *request, sess *session) (*httpclient.Client, error) {
+	c := httpclient.New().To(r.method, r.url).SetTimeout(*timeout).SetRetries(*retries)
+
+	credentials := *auth
+	if sess != nil {
+		for k, vs := range sess.Header {
+			for _, v := range vs {
+				c.AddHeader(k, v)
+			}
+		}
+
+		c.SetCookies(sess.cookies())
+
+		if credentials == "" {
+			credentials = sess.Auth
+		} else {
+			sess.Auth = credentials
+		}
+	}
+
+	if credentials != "" {
+		user, password := credentials, ""
+		if i := strings.IndexByte(credentials, ':'); i >= 0 {
+			user, password = credentials[:i], credentials[i+1:]
+		}
+		c.SetAuth(user, password)
+	}
+
+	var (
+		fields   = make(map[string]json.RawMessage)
+		keys     []string
+		body     []byte
+		formData bool
+	)
+
+	for _, it := range r.items {
+		switch it.kind {
+		case headerItem:
+			c.SetHeader(it.key, it.value)
+		case queryItem:
+			c.AddQuery(it.key, it.value)
+		case bodyItem:
+			b, err := ioutil.ReadFile(it.value)
+			if err != nil {
+				return nil, err
+			}
+			body = b
+		case fileItem:
+			if !*form {
+				return nil, fmt.Errorf("file upload %s@%s requires -form", it.key, it.value)
+			}
+			c.AttachFile(it.key, it.value, "")
+		case dataItem:
+			if *form {
+				c.AddField(it.key, it.value)
+				formData = true
+				continue
+			}
+			v, _ := json.Marshal(it.value)
+			keys = appendKey(keys, it.key)
+			fields[it.key] = v
+		case jsonItem:
+			if *form {
+				return nil, fmt.Errorf("raw JSON field %s:= requires a JSON body", it.key)
+			}
+			keys = appendKey(keys, it.key)
+			fields[it.key] = it.raw
+		}
+	}
+
+	switch {
+	case body != nil:
+		if len(fields) > 0 {
+			return nil, fmt.Errorf("@file body can not be combined with data items")
+		}
+		c.SendBody(string(body))
+		if !json.Valid(body) {
+			c.SetContentType(http.DetectContentType(body))
+		}
+	case formData:
+		c.SetContentType("form")
+	case len(fields) > 0:
+		c.SendBody(jsonObject(keys, fields))
+		c.SetHeader("Accept", "application/json, */*;q=0.5")
+	}
+
+	return c, nil
+}
+
+func appendKey(keys []string, key string) []string {
+	for _, k := range keys {
+		if k == key {
+			return keys
+		}
+	}
+	return append(keys, key)
+}
+
+// jsonObject encodes the fields in the order they were given.
+func jsonObject(keys []string, fields map[string]json.RawMessage) string {
+	var b bytes.Buffer
+	b.WriteByte('{')
+
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(k)
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(fields[k])
+	}
+
+	b.WriteByte('}')
+	return b.String()
+}
+
+// requestPrinter prints the requests logged by the package for -v, the
+// credentials are redacted.
+type requestPrinter struct {
+	w io.Writer
+}
+
+func (p requestPrinter) Log(ctx context.Context, r *httpclient.LogRecord) {
+	switch r.Message {
+	case "request":
+		fmt.Fprintf(p.w, "%s %s\n", r.Method, r.URL)
+		printHeader(p.w, r.Header)
+
+		if len(r.Body) > 0 {
+			fmt.Fprintf(p.w, "\n%s\n", r.Body)
+		}
+
+		fmt.Fprintln(p.w)
+	case "retry":
+		fmt.Fprintf(os.Stderr, "attempt %d failed: %v, retrying\n", r.Attempt, r.Err)
+	}
+}
+
+func printResponse(w io.Writer, res *httpclient.Response) error {
+	if !*bodyOnly {
+		fmt.Fprintf(w, "%s %s\n", res.Proto, res.Status)
+		printHeader(w, res.Header)
+
+		if *headersOnly {
+			return nil
+		}
+		fmt.Fprintln(w)
+	}
+
+	b, err := res.Content()
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(res.ContentType(), "json") {
+		var out bytes.Buffer
+		if json.Indent(&out, b, "", "  ") == nil {
+			b = out.Bytes()
+		}
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+
+	if len(b) > 0 && b[len(b)-1] != '\n' {
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
+
+func printHeader(w io.Writer, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(w, "%s: %s\n", k, v)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// session is the state persisted between runs with -session.
+type session struct {
+	path string
+
+	Header  http.Header `json:"header,omitempty"`
+	Cookies []cookie    `json:"cookies,omitempty"`
+	Auth    string      `json:"auth,omitempty"`
+}
+
+type cookie struct {
+	Name    string     `json:"name"`
+	Value   string     `json:"value"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+func (s *session) cookies() []*http.Cookie {
+	now := time.Now()
+
+	var cookies []*http.Cookie
+	for _, c := range s.Cookies {
+		if c.Expires == nil || c.Expires.After(now) {
+			cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+	return cookies
+}
+
+// headers which describe a single request or carry credentials and are
+// never persisted, -auth is persisted instead of Authorization.
+var transientHeaders = []string{"Authorization", "Cookie", "Content-Type", "Content-Length", "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"}
+
+func sessionPath(name, rawurl string) (string, error) {
+	if strings.ContainsRune(name, os.PathSeparator) || strings.ContainsRune(name, '/') {
+		return name, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+
+	host := strings.Replace(u.Host, ":", "_", -1)
+	return filepath.Join(dir, "httpclient", "sessions", host, name+".json"), nil
+}
+
+func loadSession(name, rawurl string) (*session, error) {
+	path, err := sessionPath(name, rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &session{path: path, Header: make(http.Header)}
+
+	b, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+
+	if s.Header == nil {
+		s.Header = make(http.Header)
+	}
+
+	return s, nil
+}
+
+// update stores the headers given on the command line and the cookies
+// received.
+func (s *session) update(given http.Header, received []*http.Cookie) {
+	for k, vs := range given {
+		s.Header[k] = vs
+	}
+
+	for _, k := range transientHeaders {
+		s.Header.Del(k)
+	}
+
+	now := time.Now()
+
+	for _, c := range received {
+		kept := s.Cookies[:0]
+		for _, old := range s.Cookies {
+			if old.Name != c.Name {
+				kept = append(kept, old)
+			}
+		}
+		s.Cookies = kept
+
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(now)) {
+			continue
+		}
+
+		stored := cookie{Name: c.Name, Value: c.Value}
+		switch {
+		case c.MaxAge > 0:
+			t := now.Add(time.Duration(c.MaxAge) * time.Second)
+			stored.Expires = &t
+		case !c.Expires.IsZero():
+			t := c.Expires
+			stored.Expires = &t
+		}
+		s.Cookies = append(s.Cookies, stored)
+	}
+}
+
+func (s *session) save() error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.path, b, 0600)
+}
@@ -0,0 +1,540 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type pathOperation struct {
+	method string
+	path   string
+	item   *pathItem
+	op     *operation
+}
+
+func (g *generator) operations() []pathOperation {
+	paths := make([]string, 0, len(g.doc.Paths))
+	for p := range g.doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var ops []pathOperation
+	for _, p := range paths {
+		item := g.doc.Paths[p]
+		for _, mo := range []struct {
+			method string
+			op     *operation
+		}{
+			{http.MethodGet, item.Get},
+			{http.MethodPut, item.Put},
+			{http.MethodPost, item.Post},
+			{http.MethodDelete, item.Delete},
+			{http.MethodOptions, item.Options},
+			{http.MethodHead, item.Head},
+			{http.MethodPatch, item.Patch},
+		} {
+			if mo.op != nil {
+				ops = append(ops, pathOperation{mo.method, p, item, mo.op})
+			}
+		}
+	}
+
+	return ops
+}
+
+// generate returns the formatted source of the client.
+func (g *generator) generate(source string) ([]byte, error) {
+	g.genSchemas()
+
+	g.clientType = g.reserve("Client", "APIClient")
+	g.errorType = g.reserve("Error", "APIError")
+
+	var methods bytes.Buffer
+	for _, po := range g.operations() {
+		if err := g.genOperation(&methods, po); err != nil {
+			return nil, fmt.Errorf("%s %s: %v", po.method, po.path, err)
+		}
+	}
+
+	var client bytes.Buffer
+	g.genClientType(&client)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by openapi-gen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&buf, "package %s\n\n", g.pkg)
+
+	g.imports["encoding/json"] = true
+	g.imports["fmt"] = true
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
+	buf.WriteString("import (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&buf, "\t%q\n", imp)
+	}
+	buf.WriteString("\n\t\"github.com/lets-go-go/httpclient\"\n)\n\n")
+
+	if len(g.doc.Servers) > 0 {
+		fmt.Fprintf(&buf, "// DefaultServerURL is the first server declared by the document.\n")
+		fmt.Fprintf(&buf, "const DefaultServerURL = %q\n\n", g.doc.Servers[0].URL)
+	}
+
+	buf.Write(client.Bytes())
+	for _, d := range g.decls {
+		buf.WriteString(d)
+	}
+	buf.Write(methods.Bytes())
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes(), fmt.Errorf("formatting generated code: %v", err)
+	}
+
+	return code, nil
+}
+
+// reserve returns name, or alt followed by a number if needed when a
+// declared type already uses it, and marks it as declared.
+func (g *generator) reserve(name, alt string) string {
+	for i := 1; g.named[name]; i++ {
+		name = alt
+		if i > 1 {
+			name += strconv.Itoa(i)
+		}
+	}
+
+	g.named[name] = true
+	return name
+}
+
+func (g *generator) genClientType(w *bytes.Buffer) {
+	title := strings.TrimSpace(g.doc.Info.Title + " " + g.doc.Info.Version)
+
+	ct := g.clientType
+	fmt.Fprintf(w, "// %s is a client for %s.\n", ct, title)
+	fmt.Fprintf(w, "type %s struct {\n\tsession *httpclient.Session\n\tcredentials map[string][]string\n}\n\n", ct)
+	fmt.Fprintf(w, "// NewClient returns a %s which sends its requests through session.\n", ct)
+	fmt.Fprintf(w, "func NewClient(session *httpclient.Session) *%s {\n", ct)
+	fmt.Fprintf(w, "\treturn &%s{session: session, credentials: make(map[string][]string)}\n}\n\n", ct)
+
+	names := make([]string, 0, len(g.doc.Components.SecuritySchemes))
+	for name := range g.doc.Components.SecuritySchemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var apply bytes.Buffer
+	for _, name := range names {
+		ss := g.doc.Components.SecuritySchemes[name]
+		setter := "Set" + goName(name)
+
+		switch {
+		case ss.Type == "http" && strings.EqualFold(ss.Scheme, "basic"):
+			fmt.Fprintf(w, "// %s sets the credentials of the %q security scheme.\n", setter, name)
+			fmt.Fprintf(w, "func (c *%s) %s(username, password string) *%s {\n", ct, setter, ct)
+			fmt.Fprintf(w, "\tc.credentials[%q] = []string{username, password}\n\treturn c\n}\n\n", name)
+			fmt.Fprintf(&apply, "\t\tcase %q:\n\t\t\treq.SetAuth(cred[0], cred[1])\n", name)
+			continue
+		case ss.Type == "apiKey":
+			fmt.Fprintf(w, "// %s sets the API key of the %q security scheme.\n", setter, name)
+			switch ss.In {
+			case "query":
+				fmt.Fprintf(&apply, "\t\tcase %q:\n\t\t\treq.AddQuery(%q, cred[0])\n", name, ss.Name)
+			case "cookie":
+				g.imports["net/http"] = true
+				fmt.Fprintf(&apply, "\t\tcase %q:\n\t\t\treq.AddCookie(&http.Cookie{Name: %q, Value: cred[0]})\n", name, ss.Name)
+			default:
+				fmt.Fprintf(&apply, "\t\tcase %q:\n\t\t\treq.SetHeader(%q, cred[0])\n", name, ss.Name)
+			}
+		case ss.Type == "http":
+			fmt.Fprintf(w, "// %s sets the token of the %q security scheme.\n", setter, name)
+			scheme := ss.Scheme
+			if strings.EqualFold(scheme, "bearer") {
+				scheme = "Bearer"
+			}
+			fmt.Fprintf(&apply, "\t\tcase %q:\n\t\t\treq.SetHeader(\"Authorization\", %q+cred[0])\n", name, scheme+" ")
+		default:
+			// oauth2 and openIdConnect send the access token as a bearer token
+			fmt.Fprintf(w, "// %s sets the access token of the %q security scheme.\n", setter, name)
+			fmt.Fprintf(&apply, "\t\tcase %q:\n\t\t\treq.SetHeader(\"Authorization\", \"Bearer \"+cred[0])\n", name)
+		}
+
+		fmt.Fprintf(w, "func (c *%s) %s(value string) *%s {\n", ct, setter, ct)
+		fmt.Fprintf(w, "\tc.credentials[%q] = []string{value}\n\treturn c\n}\n\n", name)
+	}
+
+	if apply.Len() > 0 {
+		fmt.Fprintf(w, `// authorize applies the credentials of the first security requirement
+// whose schemes all have credentials set.
+func (c *%s) authorize(req *httpclient.Client, requirements [][]string) {
+	for _, names := range requirements {
+		ok := true
+		for _, name := range names {
+			ok = ok && c.credentials[name] != nil
+		}
+		if !ok {
+			continue
+		}
+
+		for _, name := range names {
+			cred := c.credentials[name]
+			switch name {
+%s			}
+		}
+		return
+	}
+}
+
+`, ct, apply.String())
+	}
+
+	et := g.errorType
+	fmt.Fprintf(w, `// %[2]s is returned for responses with a non-2xx status code.
+type %[2]s struct {
+	StatusCode int
+	Body       []byte
+	// Model is the decoded error body if the document declares one.
+	Model interface{}
+}
+
+func (e *%[2]s) Error() string {
+	return fmt.Sprintf("%[1]s: status code %%d: %%s", e.StatusCode, e.Body)
+}
+
+// newError reads and closes the body of res.
+func newError(res *httpclient.Response, model interface{}) error {
+	b, _ := res.Content()
+	e := &%[2]s{StatusCode: res.StatusCode, Body: b}
+
+	if model != nil && json.Unmarshal(b, model) == nil {
+		e.Model = model
+	}
+
+	return e
+}
+
+`, g.pkg, et)
+
+	if g.hasUnions() {
+		g.imports["bytes"] = true
+		fmt.Fprintf(w, `func decodeStrict(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+`)
+	}
+}
+
+func (g *generator) hasUnions() bool {
+	for _, d := range g.decls {
+		if strings.Contains(d, "decodeStrict(") {
+			return true
+		}
+	}
+	return false
+}
+
+func operationName(po pathOperation) string {
+	if po.op.OperationID != "" {
+		return goName(po.op.OperationID)
+	}
+
+	name := strings.ToLower(po.method)
+	for _, seg := range strings.Split(po.path, "/") {
+		if strings.HasPrefix(seg, "{") {
+			seg = "by " + strings.Trim(seg, "{}")
+		}
+		name += " " + seg
+	}
+	return goName(name)
+}
+
+func (g *generator) genOperation(w *bytes.Buffer, po pathOperation) error {
+	name := operationName(po)
+
+	// operation parameters override the path item parameters
+	var params []*parameter
+	seen := make(map[string]int)
+	for _, p := range append(append([]*parameter(nil), po.item.Parameters...), po.op.Parameters...) {
+		p = g.doc.parameter(p)
+		key := p.In + " " + p.Name
+		if i, ok := seen[key]; ok {
+			params[i] = p
+			continue
+		}
+		seen[key] = len(params)
+		params = append(params, p)
+	}
+
+	var (
+		args    []string
+		binds   bytes.Buffer
+		fields  bytes.Buffer
+		headers bytes.Buffer
+	)
+
+	for _, v := range templateVars(po.path) {
+		var p *parameter
+		for _, q := range params {
+			if q.In == "path" && q.Name == v {
+				p = q
+			}
+		}
+
+		typ := "string"
+		if p != nil {
+			typ = g.goType(p.Schema, name+goName(v))
+		}
+
+		arg := varName(v)
+		args = append(args, arg+" "+typ)
+		fmt.Fprintf(&binds, "\treq.PathParam(%q, %s)\n", v, arg)
+	}
+
+	for _, p := range params {
+		if p.In == "path" {
+			continue
+		}
+
+		field := goName(p.Name)
+		typ := g.goType(p.Schema, name+field)
+		ptr := !p.Required && optionalPointer(typ)
+		if ptr {
+			typ = "*" + typ
+		}
+
+		tag := `url:"-"`
+		if p.In == "query" {
+			opts := ""
+			if !p.Required {
+				opts = ",omitempty"
+			}
+			if p.Explode != nil && !*p.Explode {
+				switch p.Style {
+				case "spaceDelimited":
+					opts += ",space"
+				case "pipeDelimited":
+					// not supported by StructValues, falls back to repeated keys
+				default:
+					opts += ",comma"
+				}
+			}
+			if strings.HasPrefix(typ, "time.") || strings.HasPrefix(typ, "*time.") {
+				g.imports["time"] = true
+			}
+			tag = fmt.Sprintf(`url:"%s%s"`, p.Name, opts)
+		}
+
+		comment(&fields, field, p.Description)
+		fmt.Fprintf(&fields, "\t%s %s `%s`\n", field, typ, tag)
+
+		var set string
+		switch p.In {
+		case "header":
+			set = fmt.Sprintf("req.SetHeader(%q, fmt.Sprint(%%s))", p.Name)
+		case "cookie":
+			g.imports["net/http"] = true
+			set = fmt.Sprintf("req.AddCookie(&http.Cookie{Name: %q, Value: fmt.Sprint(%%s)})", p.Name)
+		default:
+			// query parameters are added by QueryStruct
+			continue
+		}
+
+		if ptr {
+			fmt.Fprintf(&headers, "\t\tif params.%s != nil {\n\t\t\t"+set+"\n\t\t}\n", field, "*params."+field)
+		} else {
+			fmt.Fprintf(&headers, "\t\t"+set+"\n", "params."+field)
+		}
+	}
+
+	if fields.Len() > 0 {
+		typ := name + "Params"
+		g.decls = append(g.decls, fmt.Sprintf("// %s holds the query, header and cookie parameters of %s.\ntype %s struct {\n%s}\n\n", typ, name, typ, fields.String()))
+		args = append(args, "params *"+typ)
+		fmt.Fprintf(&binds, "\tif params != nil {\n\t\treq.QueryStruct(params)\n%s\t}\n", headers.String())
+	}
+
+	if rb := g.doc.requestBody(po.op.RequestBody); rb != nil {
+		arg, bind := g.requestBody(name, rb)
+		args = append(args, arg)
+		binds.WriteString(bind)
+	}
+
+	security := g.doc.Security
+	if po.op.Security != nil {
+		security = *po.op.Security
+	}
+	if len(security) > 0 && len(g.doc.Components.SecuritySchemes) > 0 {
+		var reqs []string
+		for _, req := range security {
+			names := make([]string, 0, len(req))
+			for n := range req {
+				names = append(names, strconv.Quote(n))
+			}
+			sort.Strings(names)
+			reqs = append(reqs, "{"+strings.Join(names, ", ")+"}")
+		}
+		fmt.Fprintf(&binds, "\tc.authorize(req, [][]string{%s})\n", strings.Join(reqs, ", "))
+	}
+
+	result, decode := g.result(name, po.op)
+	errModel := g.errorModel(name, po.op)
+
+	doc := po.op.Summary
+	if doc == "" {
+		doc = po.op.Description
+	}
+	if doc == "" {
+		doc = fmt.Sprintf("sends %s %s.", po.method, po.path)
+	}
+	comment(w, name, doc)
+	if po.op.Deprecated {
+		fmt.Fprintf(w, "//\n// Deprecated: %s is deprecated by the API.\n", name)
+	}
+
+	zero := "nil"
+	if result == "" {
+		fmt.Fprintf(w, "func (c *%s) %s(%s) error {\n", g.clientType, name, strings.Join(args, ", "))
+	} else {
+		fmt.Fprintf(w, "func (c *%s) %s(%s) (%s, error) {\n", g.clientType, name, strings.Join(args, ", "), result)
+	}
+
+	fmt.Fprintf(w, "\treq := c.session.To(%q, %q)\n", po.method, po.path)
+	w.Write(binds.Bytes())
+
+	ret := func(v string) string {
+		if result == "" {
+			return v
+		}
+		return zero + ", " + v
+	}
+
+	fmt.Fprintf(w, "\n\tres, err := req.Execute()\n\tif err != nil {\n\t\treturn %s\n\t}\n", ret("err"))
+	fmt.Fprintf(w, "\tif res.StatusCode/100 != 2 {\n\t\treturn %s\n\t}\n", ret("newError(res, "+errModel+")"))
+
+	if result == "" {
+		// Raw drains and closes the body so the connection is reused
+		fmt.Fprintf(w, "\n\t_, err = res.Raw()\n\treturn err\n}\n\n")
+		return nil
+	}
+
+	w.WriteString(decode)
+	return nil
+}
+
+func (g *generator) requestBody(name string, rb *requestBody) (arg, bind string) {
+	if s, ok := jsonSchema(rb.Content); ok {
+		typ := g.goType(s, name+"Request")
+		if typ == "string" {
+			// SendBody sends strings as they are, a pointer is marshaled
+			return "body string", "\treq.SendBody(&body)\n"
+		}
+		return "body " + typ, "\treq.SendBody(body)\n"
+	}
+
+	if _, ok := rb.Content["application/x-www-form-urlencoded"]; ok {
+		g.imports["net/url"] = true
+		return "body url.Values", "\treq.AddFields(body)\n"
+	}
+
+	if _, ok := rb.Content["multipart/form-data"]; ok {
+		g.imports["net/url"] = true
+		return "fields url.Values, files map[string]string",
+			"\treq.SetMultipart()\n" +
+				"\tfor k, vs := range fields {\n\t\tfor _, v := range vs {\n\t\t\treq.AddField(k, v)\n\t\t}\n\t}\n" +
+				"\tfor field, path := range files {\n\t\treq.AttachFile(field, path, \"\")\n\t}\n"
+	}
+
+	ct := "application/octet-stream"
+	for k := range rb.Content {
+		ct = k
+		break
+	}
+	return "body string", fmt.Sprintf("\treq.SendBody(body).SetContentType(%q)\n", ct)
+}
+
+// result returns the result type of the operation and the code decoding it,
+// the result is empty when the success response has no content.
+func (g *generator) result(name string, op *operation) (string, string) {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		r := g.doc.response(op.Responses[code])
+		if r == nil || len(r.Content) == 0 {
+			continue
+		}
+
+		s, ok := jsonSchema(r.Content)
+		if !ok {
+			return "[]byte", "\n\treturn res.Content()\n}\n\n"
+		}
+
+		typ := g.goType(s, name+"Response")
+		if strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "interface{}" {
+			return typ, fmt.Sprintf("\n\tvar out %s\n\tif err := res.Decode(&out, nil); err != nil {\n\t\treturn nil, err\n\t}\n\n\treturn out, nil\n}\n\n", typ)
+		}
+
+		return "*" + typ, fmt.Sprintf("\n\tout := new(%s)\n\tif err := res.Decode(out, nil); err != nil {\n\t\treturn nil, err\n\t}\n\n\treturn out, nil\n}\n\n", typ)
+	}
+
+	return "", ""
+}
+
+// errorModel returns the expression allocating the model of the error
+// responses of op.
+func (g *generator) errorModel(name string, op *operation) string {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		if !strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if r := g.doc.response(op.Responses[code]); r != nil {
+			if s, ok := jsonSchema(r.Content); ok && s != nil {
+				return "new(" + g.goType(s, name+"Error") + ")"
+			}
+		}
+	}
+
+	return "nil"
+}
+
+// templateVars returns the variable names of a path template in order.
+func templateVars(path string) []string {
+	var vars []string
+
+	for {
+		i := strings.IndexByte(path, '{')
+		if i < 0 {
+			return vars
+		}
+
+		j := strings.IndexByte(path[i:], '}')
+		if j < 0 {
+			return vars
+		}
+
+		vars = append(vars, path[i+1:i+j])
+		path = path[i+j+1:]
+	}
+}
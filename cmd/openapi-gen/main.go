@@ -0,0 +1,79 @@
+// Command openapi-gen generates a typed Go client from an OpenAPI 3
+// document in JSON or YAML format. The generated code uses httpclient for
+// transport:
+//
+//	session := httpclient.NewSession().SetBaseURL(petstore.DefaultServerURL)
+//	api := petstore.NewClient(session).SetBearerAuth(token)
+//	pet, err := api.GetPetByID(42)
+//
+// For every schema in components a type is declared: objects become
+// structs, enums become named types with constants, and oneOf/anyOf become
+// structs holding one pointer per variant which are decoded using the
+// discriminator when present. For every operation a method is generated
+// taking the path parameters, a params struct with the query, header and
+// cookie parameters and the request body. Non-2xx responses are returned as
+// *Error with the declared error schema decoded into Error.Model. When a
+// schema is named Client or Error, the generated client and error types are
+// named APIClient and APIError instead.
+//
+// Security schemes of type apiKey, http (basic and bearer), oauth2 and
+// openIdConnect get a setter on the client; the credentials are applied to
+// the operations requiring them.
+//
+// Usage:
+//
+//	//go:generate go run github.com/lets-go-go/httpclient/cmd/openapi-gen -package petstore -o client.go petstore.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var (
+	pkgName = flag.String("package", "", "package name of the generated code; default the output directory name")
+	output  = flag.String("o", "client.go", "output file name")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: openapi-gen [-package name] [-o file] spec.(json|yaml)\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *pkgName, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "openapi-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(spec, pkg, out string) error {
+	doc, err := loadDocument(spec)
+	if err != nil {
+		return err
+	}
+
+	if pkg == "" {
+		abs, err := filepath.Abs(out)
+		if err != nil {
+			return err
+		}
+		pkg = filepath.Base(filepath.Dir(abs))
+	}
+
+	code, err := newGenerator(doc, pkg).generate(filepath.Base(spec))
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(out, code, 0644)
+}
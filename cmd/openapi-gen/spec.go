@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// document is the subset of an OpenAPI 3 document used by the generator.
+type document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       info                  `json:"info"`
+	Servers    []server              `json:"servers"`
+	Paths      map[string]*pathItem  `json:"paths"`
+	Components components            `json:"components"`
+	Security   []map[string][]string `json:"security"`
+}
+
+type info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type server struct {
+	URL string `json:"url"`
+}
+
+type components struct {
+	Schemas         map[string]*schema         `json:"schemas"`
+	Parameters      map[string]*parameter      `json:"parameters"`
+	RequestBodies   map[string]*requestBody    `json:"requestBodies"`
+	Responses       map[string]*response       `json:"responses"`
+	SecuritySchemes map[string]*securityScheme `json:"securitySchemes"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `json:"parameters"`
+	Get        *operation   `json:"get"`
+	Put        *operation   `json:"put"`
+	Post       *operation   `json:"post"`
+	Delete     *operation   `json:"delete"`
+	Options    *operation   `json:"options"`
+	Head       *operation   `json:"head"`
+	Patch      *operation   `json:"patch"`
+}
+
+type operation struct {
+	OperationID string                 `json:"operationId"`
+	Summary     string                 `json:"summary"`
+	Description string                 `json:"description"`
+	Deprecated  bool                   `json:"deprecated"`
+	Parameters  []*parameter           `json:"parameters"`
+	RequestBody *requestBody           `json:"requestBody"`
+	Responses   map[string]*response   `json:"responses"`
+	Security    *[]map[string][]string `json:"security"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Style       string  `json:"style"`
+	Explode     *bool   `json:"explode"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Required    bool                  `json:"required"`
+	Content     map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Content     map[string]*mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description"`
+	Name         string `json:"name"`
+	In           string `json:"in"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat"`
+}
+
+type schema struct {
+	Ref                  string                `json:"$ref"`
+	Type                 schemaType            `json:"type"`
+	Format               string                `json:"format"`
+	Description          string                `json:"description"`
+	Enum                 []interface{}         `json:"enum"`
+	Items                *schema               `json:"items"`
+	Properties           map[string]*schema    `json:"properties"`
+	Required             []string              `json:"required"`
+	AdditionalProperties *additionalProperties `json:"additionalProperties"`
+	OneOf                []*schema             `json:"oneOf"`
+	AnyOf                []*schema             `json:"anyOf"`
+	AllOf                []*schema             `json:"allOf"`
+	Discriminator        *discriminator        `json:"discriminator"`
+	Nullable             bool                  `json:"nullable"`
+}
+
+type discriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping"`
+}
+
+// schemaType is the "type" of a schema. OpenAPI 3.1 allows a list of types,
+// "null" is dropped from it and only the first remaining type is kept.
+type schemaType string
+
+func (t *schemaType) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = schemaType(s)
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+
+	for _, s := range list {
+		if s != "null" {
+			*t = schemaType(s)
+			break
+		}
+	}
+
+	return nil
+}
+
+// additionalProperties is either a boolean or a schema.
+type additionalProperties struct {
+	Allowed bool
+	Schema  *schema
+}
+
+func (a *additionalProperties) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &a.Allowed); err == nil {
+		return nil
+	}
+
+	a.Allowed = true
+	return json.Unmarshal(b, &a.Schema)
+}
+
+// loadDocument reads an OpenAPI document in JSON or YAML format.
+func loadDocument(filename string) (*document, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".json" && !bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		var v interface{}
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+
+		if b, err = json.Marshal(normalizeYAML(v)); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := new(document)
+	if err := json.Unmarshal(b, doc); err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", doc.OpenAPI)
+	}
+
+	return doc, nil
+}
+
+// normalizeYAML converts the maps decoded by yaml, whose keys may be
+// non-strings such as response codes, into values encoding/json accepts.
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeYAML(e)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalizeYAML(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeYAML(e)
+		}
+		return v
+	}
+
+	return v
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndexByte(ref, '/')+1:]
+}
+
+func (d *document) parameter(p *parameter) *parameter {
+	if p.Ref != "" {
+		if r := d.Components.Parameters[refName(p.Ref)]; r != nil {
+			return r
+		}
+	}
+	return p
+}
+
+func (d *document) requestBody(b *requestBody) *requestBody {
+	if b != nil && b.Ref != "" {
+		if r := d.Components.RequestBodies[refName(b.Ref)]; r != nil {
+			return r
+		}
+	}
+	return b
+}
+
+func (d *document) response(r *response) *response {
+	if r != nil && r.Ref != "" {
+		if res := d.Components.Responses[refName(r.Ref)]; res != nil {
+			return res
+		}
+	}
+	return r
+}
+
+// jsonSchema returns the schema of the JSON media type of content.
+func jsonSchema(content map[string]*mediaType) (*schema, bool) {
+	for ct, mt := range content {
+		if ct == "application/json" || strings.HasSuffix(ct, "+json") {
+			if mt == nil {
+				return nil, true
+			}
+			return mt.Schema, true
+		}
+	}
+	return nil, false
+}
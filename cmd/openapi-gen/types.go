@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// generator accumulates the declarations of the generated file.
+type generator struct {
+	doc     *document
+	pkg     string
+	decls   []string
+	named   map[string]bool
+	imports map[string]bool
+
+	// names of the client and error types, renamed when a schema uses them
+	clientType string
+	errorType  string
+}
+
+func newGenerator(doc *document, pkg string) *generator {
+	return &generator{
+		doc:     doc,
+		pkg:     pkg,
+		named:   make(map[string]bool),
+		imports: make(map[string]bool),
+	}
+}
+
+// genSchemas declares a type for every schema in components.
+func (g *generator) genSchemas() {
+	names := make([]string, 0, len(g.doc.Components.Schemas))
+	for name := range g.doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		g.declare(goName(name), g.doc.Components.Schemas[name])
+	}
+}
+
+// declare emits a named type for s. Types it depends on are declared
+// before it.
+func (g *generator) declare(name string, s *schema) {
+	if g.named[name] {
+		return
+	}
+	g.named[name] = true
+
+	var w bytes.Buffer
+	comment(&w, name, s.Description)
+
+	switch {
+	case s.Ref != "":
+		fmt.Fprintf(&w, "type %s = %s\n\n", name, g.goType(s, name+"Alias"))
+	case len(s.Enum) > 0:
+		g.genEnum(&w, name, s)
+	case len(s.OneOf) > 0:
+		g.genUnion(&w, name, s, s.OneOf)
+	case len(s.AnyOf) > 0:
+		g.genUnion(&w, name, s, s.AnyOf)
+	case isObject(s) && len(s.Properties)+len(s.AllOf) > 0:
+		g.genStruct(&w, name, s)
+	default:
+		fmt.Fprintf(&w, "type %s %s\n\n", name, g.goType(s, name+"Item"))
+	}
+
+	g.decls = append(g.decls, w.String())
+}
+
+// goType returns the Go type for s, declaring named types for inline
+// objects, enums and unions using hint as their name.
+func (g *generator) goType(s *schema, hint string) string {
+	if s == nil {
+		return "interface{}"
+	}
+
+	if s.Ref != "" {
+		return goName(refName(s.Ref))
+	}
+
+	if len(s.Enum) > 0 || len(s.OneOf) > 0 || len(s.AnyOf) > 0 ||
+		(isObject(s) && len(s.Properties)+len(s.AllOf) > 0) {
+		if len(s.AllOf) == 1 && len(s.Properties) == 0 {
+			return g.goType(s.AllOf[0], hint)
+		}
+		g.declare(hint, s)
+		return hint
+	}
+
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time"
+		case "byte":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		if s.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(s.Items, hint+"Item")
+	case "object", "":
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties.Schema, hint+"Value")
+		}
+		if s.Type == "object" {
+			return "map[string]interface{}"
+		}
+	}
+
+	return "interface{}"
+}
+
+func isObject(s *schema) bool {
+	return s.Type == "object" || (s.Type == "" && (len(s.Properties) > 0 || len(s.AllOf) > 0))
+}
+
+func (g *generator) genStruct(w *bytes.Buffer, name string, s *schema) {
+	var fields bytes.Buffer
+
+	for _, sub := range s.AllOf {
+		if sub.Ref != "" {
+			fmt.Fprintf(&fields, "\t%s\n", goName(refName(sub.Ref)))
+			continue
+		}
+		g.writeFields(&fields, name, sub)
+	}
+
+	g.writeFields(&fields, name, s)
+
+	fmt.Fprintf(w, "type %s struct {\n%s}\n\n", name, fields.String())
+}
+
+func (g *generator) writeFields(w *bytes.Buffer, parent string, s *schema) {
+	required := make(map[string]bool)
+	for _, r := range s.Required {
+		required[r] = true
+	}
+
+	props := make([]string, 0, len(s.Properties))
+	for p := range s.Properties {
+		props = append(props, p)
+	}
+	sort.Strings(props)
+
+	for _, p := range props {
+		ps := s.Properties[p]
+		field := goName(p)
+		typ := g.goType(ps, parent+field)
+
+		tag := p
+		if !required[p] {
+			tag += ",omitempty"
+			if optionalPointer(typ) {
+				typ = "*" + typ
+			}
+		}
+
+		if ps.Description != "" {
+			comment(w, field, ps.Description)
+		}
+		fmt.Fprintf(w, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+}
+
+// optionalPointer reports whether an optional field of type typ is declared
+// as a pointer so that zero values can be told apart from missing ones.
+func optionalPointer(typ string) bool {
+	return !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "interface{}"
+}
+
+func (g *generator) genEnum(w *bytes.Buffer, name string, s *schema) {
+	base := "string"
+	switch s.Type {
+	case "integer":
+		base = "int64"
+	case "number":
+		base = "float64"
+	}
+
+	fmt.Fprintf(w, "type %s %s\n\n", name, base)
+	fmt.Fprintf(w, "// Values of %s.\nconst (\n", name)
+
+	seen := make(map[string]bool)
+	for _, v := range s.Enum {
+		if v == nil {
+			continue
+		}
+
+		c := name + goName(fmt.Sprint(v))
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+
+		if base == "string" {
+			fmt.Fprintf(w, "\t%s %s = %s\n", c, name, strconv.Quote(fmt.Sprint(v)))
+		} else {
+			fmt.Fprintf(w, "\t%s %s = %v\n", c, name, v)
+		}
+	}
+
+	fmt.Fprintf(w, ")\n\n")
+}
+
+// genUnion declares a struct with one pointer field per variant. Exactly
+// one of them is set after decoding; the discriminator property is used
+// when the schema has one, otherwise the first variant which decodes
+// strictly wins.
+func (g *generator) genUnion(w *bytes.Buffer, name string, s *schema, variants []*schema) {
+	g.imports["encoding/json"] = true
+	g.imports["fmt"] = true
+
+	type variant struct{ field, typ string }
+	var vs []variant
+
+	for i, v := range variants {
+		typ := g.goType(v, fmt.Sprintf("%sVariant%d", name, i+1))
+		field := variantName(typ)
+		vs = append(vs, variant{field, typ})
+	}
+
+	fmt.Fprintf(w, "type %s struct {\n", name)
+	for _, v := range vs {
+		fmt.Fprintf(w, "\t%s *%s\n", v.field, v.typ)
+	}
+	fmt.Fprintf(w, "}\n\n")
+
+	fmt.Fprintf(w, "// MarshalJSON encodes the variant which is set.\n")
+	fmt.Fprintf(w, "func (u %s) MarshalJSON() ([]byte, error) {\n\tswitch {\n", name)
+	for _, v := range vs {
+		fmt.Fprintf(w, "\tcase u.%s != nil:\n\t\treturn json.Marshal(u.%s)\n", v.field, v.field)
+	}
+	fmt.Fprintf(w, "\t}\n\treturn []byte(\"null\"), nil\n}\n\n")
+
+	fmt.Fprintf(w, "// UnmarshalJSON decodes b into the matching variant.\n")
+	fmt.Fprintf(w, "func (u *%s) UnmarshalJSON(b []byte) error {\n", name)
+	fmt.Fprintf(w, "\t*u = %s{}\n\n", name)
+
+	if d := s.Discriminator; d != nil && d.PropertyName != "" {
+		fmt.Fprintf(w, "\tvar d struct {\n\t\tKind string `json:%q`\n\t}\n", d.PropertyName)
+		fmt.Fprintf(w, "\tif err := json.Unmarshal(b, &d); err != nil {\n\t\treturn err\n\t}\n\n")
+		fmt.Fprintf(w, "\tswitch d.Kind {\n")
+
+		for i, v := range vs {
+			values := []string{strconv.Quote(v.typ)}
+			if ref := variants[i].Ref; ref != "" {
+				values = []string{strconv.Quote(refName(ref))}
+				for k, m := range d.Mapping {
+					if m == ref || refName(m) == refName(ref) {
+						values = append(values, strconv.Quote(k))
+					}
+				}
+				sort.Strings(values[1:])
+			}
+			fmt.Fprintf(w, "\tcase %s:\n\t\tu.%s = new(%s)\n\t\treturn json.Unmarshal(b, u.%s)\n",
+				strings.Join(dedupe(values), ", "), v.field, v.typ, v.field)
+		}
+
+		fmt.Fprintf(w, "\t}\n\n\treturn fmt.Errorf(\"%s: unknown %s %%q\", d.Kind)\n}\n\n", name, d.PropertyName)
+		return
+	}
+
+	for _, v := range vs {
+		fmt.Fprintf(w, "\tif v := new(%s); decodeStrict(b, v) == nil {\n\t\tu.%s = v\n\t\treturn nil\n\t}\n", v.typ, v.field)
+	}
+	fmt.Fprintf(w, "\n\treturn fmt.Errorf(\"%s: no variant matches %%s\", b)\n}\n\n", name)
+}
+
+func variantName(typ string) string {
+	switch {
+	case strings.HasPrefix(typ, "[]"):
+		return variantName(typ[2:]) + "List"
+	case strings.HasPrefix(typ, "map[string]"):
+		return variantName(typ[len("map[string]"):]) + "Map"
+	case typ == "interface{}":
+		return "Any"
+	case typ == "time.Time":
+		return "Time"
+	}
+	return goName(typ)
+}
+
+func dedupe(s []string) []string {
+	seen := make(map[string]bool)
+	out := s[:0]
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func comment(w *bytes.Buffer, name, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+
+	lines := strings.Split(text, "\n")
+	if !strings.HasPrefix(lines[0], name+" ") {
+		lines[0] = name + " " + lowerFirst(lines[0])
+	}
+
+	for _, l := range lines {
+		fmt.Fprintf(w, "// %s\n", strings.TrimRight(l, " \t"))
+	}
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	if len(r) > 1 && unicode.IsUpper(r[1]) {
+		return s
+	}
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+var initialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "SQL": true, "TLS": true, "UI": true, "URI": true, "URL": true,
+	"UUID": true, "XML": true,
+}
+
+// goName converts an identifier from a document into an exported Go name,
+// "user_id", "user-id" and "userId" all become "UserID".
+func goName(s string) string {
+	var words []string
+	var cur []rune
+
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = cur[:0]
+		}
+	}
+
+	for i, r := range s {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && len(cur) > 0 && !unicode.IsUpper(cur[len(cur)-1]):
+			flush()
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if up := strings.ToUpper(w); initialisms[up] {
+			b.WriteString(up)
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+
+	name := b.String()
+	if name == "" {
+		return "Value"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "V" + name
+	}
+	return name
+}
+
+var keywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+	// names used by the generated methods
+	"c": true, "req": true, "res": true, "err": true, "out": true, "params": true, "body": true,
+}
+
+// varName converts an identifier into an unexported Go name.
+func varName(s string) string {
+	name := goName(s)
+
+	r := []rune(name)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+
+	// keep the first letter of the next word in "IDValue"
+	if n > 1 && n < len(r) {
+		n--
+	}
+
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+
+	name = string(r)
+	if keywords[name] {
+		name += "_"
+	}
+	return name
+}
@@ -0,0 +1,208 @@
+package httpclient
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultCompressionMinSize is the size below which a body is sent
+// uncompressed when Compression.MinSize is 0.
+const DefaultCompressionMinSize = 1024
+
+// Compression configures the compression of the request bodies.
+type Compression struct {
+	// Encoding is the content coding of the bodies: "gzip", "deflate" or
+	// one registered with RegisterEncoding, such as "zstd" or "br". An
+	// empty one sends the bodies uncompressed.
+	Encoding string
+	// MinSize is the size below which a body is sent uncompressed, 0 means
+	// DefaultCompressionMinSize and a negative value compresses every
+	// body. Bodies whose size is unknown, such as streams, are compressed.
+	MinSize int64
+}
+
+var (
+	encodingsMu sync.RWMutex
+	encodings   = map[string]func(w io.Writer) (io.WriteCloser, error){
+		"gzip": func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		// HTTP 的 deflate 是 zlib 格式
+		"deflate": func(w io.Writer) (io.WriteCloser, error) {
+			return zlib.NewWriter(w), nil
+		},
+	}
+)
+
+// RegisterEncoding makes a content coding available to Compression, or
+// replaces one. newWriter returns a writer compressing to w, whose Close
+// flushes the compressed data without closing w:
+//
+//	httpclient.RegisterEncoding("zstd", func(w io.Writer) (io.WriteCloser, error) {
+//		return zstd.NewWriter(w)
+//	})
+func RegisterEncoding(name string, newWriter func(w io.Writer) (io.WriteCloser, error)) {
+	encodingsMu.Lock()
+	encodings[name] = newWriter
+	encodingsMu.Unlock()
+}
+
+// SetCompression sets the default compression of the request bodies.
+func (c *ClientSetting) SetCompression(comp Compression) *ClientSetting {
+	c.compression = &comp
+	return c
+}
+
+// SetCompression sets the compression of the request bodies of the session
+// instead of the one set by Settings().SetCompression.
+func (s *Session) SetCompression(comp Compression) *Session {
+	s.compression = &comp
+	return s
+}
+
+// SetCompression sets the compression of the request body instead of the
+// one of the session and of Settings(). The body is compressed while it is
+// sent and the "Content-Encoding" header is set. A body set with SendBody
+// is held in memory before it is compressed, while the files of a form
+// built with AttachFile are streamed to the compressor.
+func (c *Client) SetCompression(comp Compression) *Client {
+	c.compression = &comp
+	return c
+}
+
+// compressionOf returns the compression of the request body.
+func (c *Client) compressionOf() Compression {
+	switch {
+	case c.compression != nil:
+		return *c.compression
+	case c.session != nil && c.session.compression != nil:
+		return *c.session.compression
+	case Settings().compression != nil:
+		return *Settings().compression
+	}
+
+	return Compression{}
+}
+
+// compress compresses the body of the assembled request as configured,
+// unless it is smaller than the threshold or already encoded.
+func (c *Client) compress() error {
+	comp := c.compressionOf()
+	req := c.req
+
+	if comp.Encoding == "" || req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	min := comp.MinSize
+	if min == 0 {
+		min = DefaultCompressionMinSize
+	}
+
+	// ContentLength 为 0 时长度未知
+	if req.ContentLength > 0 && req.ContentLength < min {
+		return nil
+	}
+
+	encodingsMu.RLock()
+	newWriter := encodings[comp.Encoding]
+	encodingsMu.RUnlock()
+
+	if newWriter == nil {
+		return fmt.Errorf("request: unknown content encoding %q", comp.Encoding)
+	}
+
+	req.Body = compressBody(req.Body, newWriter)
+	req.ContentLength = 0
+
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return compressBody(body, newWriter), nil
+		}
+
+		// HAR 和 vcr 记录压缩前的请求体
+		req = req.WithContext(context.WithValue(req.Context(), uncompressedKey{}, getBody))
+		c.req = req
+	}
+
+	// 不改动 c.header，重新组装请求时不会误认为已压缩
+	req.Header = req.Header.Clone()
+	req.Header.Set("Content-Encoding", comp.Encoding)
+	return nil
+}
+
+type uncompressedKey struct{}
+
+// UncompressedBody returns a function returning a copy of the body of req
+// as it was before SetCompression compressed it, so the transports
+// recording the requests can save it readable. It returns nil when the body
+// was not compressed by SetCompression or cannot be read again.
+func UncompressedBody(req *http.Request) func() (io.ReadCloser, error) {
+	getBody, _ := req.Context().Value(uncompressedKey{}).(func() (io.ReadCloser, error))
+	return getBody
+}
+
+// compressBody returns body compressed by a writer of newWriter as it is
+// read.
+func compressBody(body io.ReadCloser, newWriter func(w io.Writer) (io.WriteCloser, error)) io.ReadCloser {
+	return &compressedBody{body: body, newWriter: newWriter}
+}
+
+// compressedBody starts compressing on the first Read, so a request which
+// is assembled but not sent does not consume its body.
+type compressedBody struct {
+	body      io.ReadCloser
+	newWriter func(w io.Writer) (io.WriteCloser, error)
+	once      sync.Once
+	pr        *io.PipeReader
+}
+
+func (b *compressedBody) start() {
+	b.once.Do(func() {
+		pr, pw := io.Pipe()
+		b.pr = pr
+
+		go func() {
+			zw, err := b.newWriter(pw)
+			if err == nil {
+				_, err = io.Copy(zw, b.body)
+				if cerr := zw.Close(); err == nil {
+					err = cerr
+				}
+			}
+
+			b.body.Close()
+			pw.CloseWithError(err)
+		}()
+	})
+}
+
+func (b *compressedBody) Read(p []byte) (int, error) {
+	b.start()
+	if b.pr == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return b.pr.Read(p)
+}
+
+func (b *compressedBody) Close() error {
+	started := true
+	b.once.Do(func() { started = false })
+
+	if !started {
+		return b.body.Close()
+	}
+	if b.pr != nil {
+		return b.pr.Close()
+	}
+	return nil
+}
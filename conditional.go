@@ -0,0 +1,266 @@
+package httpclient
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IfNoneMatch sets the "If-None-Match" header, so a GET is answered with
+// 304 Not Modified when the representation still has one of the etags, or
+// a PUT fails when "*" is given and the resource exists. Unquoted etags are
+// quoted.
+func (c *Client) IfNoneMatch(etags ...string) *Client {
+	return c.SetHeader("If-None-Match", joinETags(etags))
+}
+
+// IfMatch sets the "If-Match" header, so the request fails with 412
+// Precondition Failed unless the representation still has one of the
+// etags, which prevents lost updates. Unquoted etags are quoted.
+func (c *Client) IfMatch(etags ...string) *Client {
+	return c.SetHeader("If-Match", joinETags(etags))
+}
+
+// IfModifiedSince sets the "If-Modified-Since" header, so a GET is answered
+// with 304 Not Modified when the representation did not change since t.
+func (c *Client) IfModifiedSince(t time.Time) *Client {
+	return c.SetHeader("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+}
+
+func joinETags(etags []string) string {
+	quoted := make([]string, len(etags))
+
+	for i, etag := range etags {
+		if etag != "*" && !strings.HasSuffix(etag, `"`) {
+			etag = `"` + etag + `"`
+		}
+		quoted[i] = etag
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// NotModified reports whether the response is a 304 Not Modified, sent to
+// a conditional request when the representation did not change.
+func (r *Response) NotModified() bool {
+	return r.StatusCode == http.StatusNotModified
+}
+
+// Validators are the validators of a representation, as sent in the
+// "ETag" and "Last-Modified" headers.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// DefaultValidatorStoreSize is the number of URLs remembered by a
+// ValidatorStore whose size is 0.
+const DefaultValidatorStoreSize = 1000
+
+// ValidatorStore remembers the validators of the last response received
+// for each URL, the least recently used URLs being forgotten beyond its
+// size. It keeps no response body, so it suits pollers skipping the
+// responses which did not change rather than serving them from a cache.
+type ValidatorStore struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // 最近使用的在前
+	entries map[string]*list.Element
+}
+
+type validatorEntry struct {
+	url        string
+	validators Validators
+}
+
+// NewValidatorStore returns a ValidatorStore remembering up to size URLs, 0
+// means DefaultValidatorStoreSize.
+func NewValidatorStore(size int) *ValidatorStore {
+	if size <= 0 {
+		size = DefaultValidatorStoreSize
+	}
+
+	return &ValidatorStore{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the validators remembered for url.
+func (s *ValidatorStore) Get(url string) (Validators, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[url]
+	if !ok {
+		return Validators{}, false
+	}
+
+	s.order.MoveToFront(e)
+	return e.Value.(*validatorEntry).validators, true
+}
+
+// Set remembers the validators of url.
+func (s *ValidatorStore) Set(url string, v Validators) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[url]; ok {
+		e.Value.(*validatorEntry).validators = v
+		s.order.MoveToFront(e)
+		return
+	}
+
+	s.entries[url] = s.order.PushFront(&validatorEntry{url: url, validators: v})
+
+	for s.order.Len() > s.size {
+		e := s.order.Back()
+		s.order.Remove(e)
+		delete(s.entries, e.Value.(*validatorEntry).url)
+	}
+}
+
+// Delete forgets the validators of url.
+func (s *ValidatorStore) Delete(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[url]; ok {
+		s.order.Remove(e)
+		delete(s.entries, url)
+	}
+}
+
+// Len returns the number of URLs remembered.
+func (s *ValidatorStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+// SetConditionalGET makes the GET and HEAD requests conditional: the
+// validators of the last response received for their URL are remembered
+// in store and sent in the "If-None-Match" and "If-Modified-Since" headers,
+// so an unchanged representation is answered with 304 Not Modified. The
+// validators of a response are remembered once its body is read to the end.
+// nil disables it.
+func (c *ClientSetting) SetConditionalGET(store *ValidatorStore) *ClientSetting {
+	c.validators = store
+	return c
+}
+
+// SetConditionalGET makes the GET and HEAD requests of the session
+// conditional, with the validators remembered in store instead of the one
+// set by Settings().SetConditionalGET:
+//
+//	s := httpclient.NewSession().SetConditionalGET(httpclient.NewValidatorStore(0))
+//	res, err := s.To("GET", "https://config.example.com/app.json").Execute()
+//	if err == nil && res.NotModified() {
+//		// 配置没有变化
+//	}
+func (s *Session) SetConditionalGET(store *ValidatorStore) *Session {
+	s.validators = store
+	return s
+}
+
+// SetConditionalGET makes the request conditional when it is a GET or a
+// HEAD, with the validators remembered in store instead of the one of the
+// session and of Settings().
+func (c *Client) SetConditionalGET(store *ValidatorStore) *Client {
+	c.validators = store
+	c.validatorsSet = true
+	return c
+}
+
+// validatorStore returns the store of the validators of the request, nil
+// when it is not conditional.
+func (c *Client) validatorStore() *ValidatorStore {
+	if c.method != http.MethodGet && c.method != http.MethodHead {
+		return nil
+	}
+
+	switch {
+	case c.validatorsSet:
+		return c.validators
+	case c.session != nil && c.session.validators != nil:
+		return c.session.validators
+	}
+
+	return Settings().validators
+}
+
+// addValidators sets the conditional headers of the request from the
+// validators remembered for its URL, unless they were set explicitly.
+func (c *Client) addValidators() {
+	store := c.validatorStore()
+	if store == nil {
+		return
+	}
+
+	v, ok := store.Get(c.req.URL.String())
+	if !ok {
+		return
+	}
+
+	// 不改动 c.header，验证器变化后重新组装请求时仍会更新
+	c.req.Header = c.req.Header.Clone()
+
+	if v.ETag != "" && c.req.Header.Get("If-None-Match") == "" {
+		c.req.Header.Set("If-None-Match", v.ETag)
+	}
+
+	if v.LastModified != "" && c.req.Header.Get("If-Modified-Since") == "" {
+		c.req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// storeValidators remembers the validators of a successful response, or
+// forgets the ones of its URL when it has none. The validators of a
+// response with a body are only valid once the whole body was received, so
+// the function remembering them is returned, to be called then.
+func (c *Client) storeValidators(res *http.Response) func() {
+	store := c.validatorStore()
+	if store == nil {
+		return nil
+	}
+
+	url := c.req.URL.String()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified:
+		// 304 可能带有更新后的验证器
+		v, _ := store.Get(url)
+		if etag := res.Header.Get("ETag"); etag != "" {
+			v.ETag = etag
+		}
+		if lm := res.Header.Get("Last-Modified"); lm != "" {
+			v.LastModified = lm
+		}
+		if v != (Validators{}) {
+			store.Set(url, v)
+		}
+	case res.StatusCode == http.StatusPartialContent:
+		// 部分内容的验证器不代表完整的表示
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		v := Validators{ETag: res.Header.Get("ETag"), LastModified: res.Header.Get("Last-Modified")}
+		save := func() {
+			if v == (Validators{}) {
+				store.Delete(url)
+				return
+			}
+			store.Set(url, v)
+		}
+
+		if c.method == http.MethodHead {
+			save()
+			return nil
+		}
+		return save
+	}
+
+	return nil
+}
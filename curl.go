@@ -0,0 +1,611 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrCurlRange is returned by Curl when a file was attached with
+// AttachFileRange, which curl options can not express.
+var ErrCurlRange = errors.New("request: file ranges can not be expressed as a curl command")
+
+// Curl returns a curl command sending the same request, without sending it.
+// Every argument is quoted for POSIX shells.
+func (c *Client) Curl() (string, error) {
+	if err := c.expand(); err != nil {
+		c.err = err
+		return "", err
+	}
+
+	if c.url == nil {
+		return "", ErrLackURL
+	}
+
+	if c.method == "" {
+		return "", ErrLackMethod
+	}
+
+	if c.err != nil {
+		return "", c.err
+	}
+
+	u := *c.url
+	u.RawQuery = c.queryVals.Encode()
+
+	multipart := len(c.files) != 0 || c.multipart
+
+	var data []string
+
+	switch {
+	case multipart:
+		keys := sortedKeys(c.formVals)
+		for _, k := range keys {
+			for _, v := range c.formVals[k] {
+				data = append(data, "--form-string", k+"="+v)
+			}
+		}
+
+		for _, f := range c.files {
+			if f.ranged {
+				return "", ErrCurlRange
+			}
+			data = append(data, "-F", f.field+"=@"+f.path+";filename="+f.filename)
+		}
+	case c.body != nil:
+		b, err := ioutil.ReadAll(c.body)
+		if err != nil {
+			return "", err
+		}
+
+		c.body = bytes.NewReader(b)
+		data = append(data, "--data-binary", string(b))
+	case len(c.formVals) > 0:
+		data = append(data, "--data-raw", c.formVals.Encode())
+	}
+
+	args := []string{"curl"}
+
+	switch {
+	case c.method == http.MethodHead:
+		// -X HEAD 会让 curl 等待响应体
+		args = append(args, "-I")
+	case c.method != http.MethodGet || len(data) > 0:
+		args = append(args, "-X", c.method)
+	}
+
+	args = append(args, u.String())
+
+	header := make(http.Header, len(c.header))
+	for k, vs := range c.header {
+		header[k] = vs
+	}
+
+	if Settings().UserAgent != "" && header.Get("User-Agent") == "" {
+		header.Set("User-Agent", Settings().UserAgent)
+	}
+
+	if multipart {
+		// curl 会生成带 boundary 的 Content-Type
+		header.Del("Content-Type")
+	}
+
+	for _, k := range sortedKeys(header) {
+		for _, v := range header[k] {
+			args = append(args, "-H", k+": "+v)
+		}
+	}
+
+	if len(c.cookies) > 0 {
+		cookies := make([]string, 0, len(c.cookies))
+		for _, cookie := range c.cookies {
+			cookies = append(cookies, (&http.Cookie{Name: cookie.Name, Value: cookie.Value}).String())
+		}
+		args = append(args, "-b", strings.Join(cookies, "; "))
+	}
+
+	if c.basicAuth != nil {
+		args = append(args, "-u", c.basicAuth.name+":"+c.basicAuth.password)
+	}
+
+	args = append(args, data...)
+
+	for i, a := range args {
+		args[i] = shellQuote(a)
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// curl options taking a value, by their short and long names.
+var curlValueOptions = map[string]string{
+	"-X":                "request",
+	"--request":         "request",
+	"-H":                "header",
+	"--header":          "header",
+	"-d":                "data",
+	"--data":            "data",
+	"--data-ascii":      "data",
+	"--data-raw":        "data-raw",
+	"--data-binary":     "data-binary",
+	"--data-urlencode":  "data-urlencode",
+	"-F":                "form",
+	"--form":            "form",
+	"--form-string":     "form-string",
+	"-u":                "user",
+	"--user":            "user",
+	"-b":                "cookie",
+	"--cookie":          "cookie",
+	"-A":                "user-agent",
+	"--user-agent":      "user-agent",
+	"-e":                "referer",
+	"--referer":         "referer",
+	"-m":                "max-time",
+	"--max-time":        "max-time",
+	"--connect-timeout": "connect-timeout",
+	"--retry":           "retry",
+	"--url":             "url",
+}
+
+// curl options without value, the ones only changing the output of curl
+// are ignored.
+var curlFlagOptions = map[string]string{
+	"-G":           "get",
+	"--get":        "get",
+	"-I":           "head",
+	"--head":       "head",
+	"-L":           "",
+	"--location":   "",
+	"-s":           "",
+	"--silent":     "",
+	"-S":           "",
+	"--show-error": "",
+	"-v":           "",
+	"--verbose":    "",
+	"-i":           "",
+	"--include":    "",
+	"-g":           "",
+	"--globoff":    "",
+	"--compressed": "",
+}
+
+// FromCurl returns a Client sending the request described by a curl
+// command, such as one copied from the developer tools of a browser. The
+// supported options are -X, -H, -d, --data-raw, --data-binary,
+// --data-urlencode, -F, --form-string, -u, -b, -A, -e, -m,
+// --connect-timeout, --retry, --url, -G and -I. Options which only change
+// the output of curl, such as -s, -v or -L, are ignored, other options are
+// an error.
+func FromCurl(command string) (*Client, error) {
+	words, err := splitShellWords(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(words) > 0 && (words[0] == "curl" || strings.HasSuffix(words[0], "/curl")) {
+		words = words[1:]
+	}
+
+	type option struct{ name, value string }
+
+	var (
+		opts    []option
+		rawurl  string
+		flags   = make(map[string]bool)
+		literal bool
+	)
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+
+		if literal || !strings.HasPrefix(w, "-") || w == "-" {
+			if rawurl != "" {
+				return nil, fmt.Errorf("request: unexpected curl argument %q", w)
+			}
+			rawurl = w
+			continue
+		}
+
+		if w == "--" {
+			literal = true
+			continue
+		}
+
+		name, value, hasValue := w, "", false
+		if strings.HasPrefix(w, "--") {
+			if j := strings.IndexByte(w, '='); j > 0 {
+				name, value, hasValue = w[:j], w[j+1:], true
+			}
+		} else if len(w) > 2 {
+			// -XPOST, -sSL
+			name = w[:2]
+			if _, ok := curlValueOptions[name]; ok {
+				value, hasValue = w[2:], true
+			} else {
+				// 值选项之后的部分是它的值: -sXPOST 即 -s -X POST
+				split := []string{name}
+				for j := 2; j < len(w); j++ {
+					opt := "-" + w[j:j+1]
+					if _, ok := curlValueOptions[opt]; ok {
+						split = append(split, opt+w[j+1:])
+						break
+					}
+					split = append(split, opt)
+				}
+
+				words = append(words[:i], append(split, words[i+1:]...)...)
+				i--
+				continue
+			}
+		}
+
+		if opt, ok := curlValueOptions[name]; ok {
+			if !hasValue {
+				if i+1 >= len(words) {
+					return nil, fmt.Errorf("request: curl option %s lacks a value", name)
+				}
+				i++
+				value = words[i]
+			}
+
+			if opt == "url" {
+				rawurl = value
+				continue
+			}
+
+			opts = append(opts, option{opt, value})
+			continue
+		}
+
+		flag, ok := curlFlagOptions[name]
+		if !ok || hasValue {
+			return nil, fmt.Errorf("request: unsupported curl option %q", w)
+		}
+
+		if flag != "" {
+			flags[flag] = true
+		}
+	}
+
+	if rawurl == "" {
+		return nil, errors.New("request: curl command lacks URL")
+	}
+
+	if !strings.Contains(rawurl, "://") {
+		rawurl = "http://" + rawurl
+	}
+
+	var (
+		method string
+		data   []string
+		form   bool
+	)
+
+	for _, o := range opts {
+		switch o.name {
+		case "request":
+			method = o.value
+		case "data", "data-binary", "data-raw":
+			d := o.value
+			if o.name != "data-raw" && strings.HasPrefix(d, "@") {
+				b, err := ioutil.ReadFile(d[1:])
+				if err != nil {
+					return nil, err
+				}
+
+				d = string(b)
+				if o.name == "data" {
+					d = strings.NewReplacer("\r", "", "\n", "").Replace(d)
+				}
+			}
+			data = append(data, d)
+		case "data-urlencode":
+			d, err := curlURLEncode(o.value)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, d)
+		case "form", "form-string":
+			form = true
+		}
+	}
+
+	if flags["get"] && len(data) > 0 {
+		sep := "?"
+		if strings.Contains(rawurl, "?") {
+			sep = "&"
+		}
+		rawurl += sep + strings.Join(data, "&")
+		data = nil
+	}
+
+	switch {
+	case method != "":
+	case flags["head"]:
+		method = http.MethodHead
+	case len(data) > 0 || form:
+		method = http.MethodPost
+	default:
+		method = http.MethodGet
+	}
+
+	c := New().To(method, rawurl)
+
+	for _, o := range opts {
+		switch o.name {
+		case "header":
+			i := strings.IndexAny(o.value, ":;")
+			if i <= 0 {
+				return nil, fmt.Errorf("request: invalid curl header %q", o.value)
+			}
+
+			key, value := strings.TrimSpace(o.value[:i]), strings.TrimSpace(o.value[i+1:])
+			if o.value[i] == ':' && value == "" {
+				// "Name:" 在 curl 中表示删除该头
+				continue
+			}
+			c.AddHeader(key, value)
+		case "user":
+			name, password := o.value, ""
+			if i := strings.IndexByte(o.value, ':'); i >= 0 {
+				name, password = o.value[:i], o.value[i+1:]
+			}
+			c.SetAuth(name, password)
+		case "cookie":
+			if !strings.Contains(o.value, "=") {
+				return nil, fmt.Errorf("request: curl cookie files are not supported: %q", o.value)
+			}
+			c.SetCookies((&http.Request{Header: http.Header{"Cookie": {o.value}}}).Cookies())
+		case "user-agent":
+			c.SetHeader("User-Agent", o.value)
+		case "referer":
+			c.SetHeader("Referer", o.value)
+		case "max-time":
+			s, err := strconv.ParseFloat(o.value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("request: invalid curl max time %q", o.value)
+			}
+			c.SetTimeout(time.Duration(s * float64(time.Second)))
+		case "connect-timeout":
+			s, err := strconv.ParseFloat(o.value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("request: invalid curl connect timeout %q", o.value)
+			}
+			c.timeouts.Connect = time.Duration(s * float64(time.Second))
+		case "retry":
+			n, err := strconv.Atoi(o.value)
+			if err != nil {
+				return nil, fmt.Errorf("request: invalid curl retry count %q", o.value)
+			}
+			c.SetRetries(n)
+		case "form", "form-string":
+			if err := c.curlFormPart(o.value, o.name == "form"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(data) > 0 {
+		if form {
+			return nil, errors.New("request: curl command mixes -d and -F")
+		}
+
+		c.body = strings.NewReader(strings.Join(data, "&"))
+		if c.header.Get("Content-Type") == "" {
+			c.SetContentType("urlencoded")
+		}
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c, nil
+}
+
+// curlFormPart adds a -F or --form-string part to the multipart form.
+func (c *Client) curlFormPart(part string, special bool) error {
+	i := strings.IndexByte(part, '=')
+	if i <= 0 {
+		return fmt.Errorf("request: invalid curl form part %q", part)
+	}
+
+	name, value := part[:i], part[i+1:]
+	c.SetMultipart()
+
+	switch {
+	case special && strings.HasPrefix(value, "@"):
+		params := strings.Split(value[1:], ";")
+		filename := ""
+
+		for _, p := range params[1:] {
+			if strings.HasPrefix(p, "filename=") {
+				filename = strings.Trim(p[len("filename="):], `"`)
+			}
+		}
+
+		c.AttachFile(name, params[0], filename)
+	case special && strings.HasPrefix(value, "<"):
+		b, err := ioutil.ReadFile(value[1:])
+		if err != nil {
+			return err
+		}
+		c.formVals.Add(name, string(b))
+	default:
+		c.formVals.Add(name, value)
+	}
+
+	return c.err
+}
+
+// curlURLEncode converts a --data-urlencode value into a query component.
+func curlURLEncode(v string) (string, error) {
+	readFile := func(path string) (string, error) {
+		b, err := ioutil.ReadFile(path)
+		return string(b), err
+	}
+
+	i := strings.IndexAny(v, "=@")
+
+	switch {
+	case i < 0:
+		return url.QueryEscape(v), nil
+	case v[i] == '=' && i == 0:
+		return url.QueryEscape(v[1:]), nil
+	case v[i] == '=':
+		return v[:i] + "=" + url.QueryEscape(v[i+1:]), nil
+	}
+
+	content, err := readFile(v[i+1:])
+	if err != nil {
+		return "", err
+	}
+
+	if i == 0 {
+		return url.QueryEscape(content), nil
+	}
+	return v[:i] + "=" + url.QueryEscape(content), nil
+}
+
+// splitShellWords splits a command line like a POSIX shell, supporting
+// single and double quotes, ANSI-C $'...' quotes, backslash escapes and
+// line continuations.
+func splitShellWords(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		runes   = []rune(s)
+		errQuot = errors.New("request: unterminated quote in curl command")
+	)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case r == '\\':
+			i++
+			if i < len(runes) && runes[i] != '\n' {
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+		case r == '\'':
+			j := i + 1
+			for j < len(runes) && runes[j] != '\'' {
+				j++
+			}
+			if j >= len(runes) {
+				return nil, errQuot
+			}
+			word.WriteString(string(runes[i+1 : j]))
+			inWord, i = true, j
+		case r == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[j+1]) {
+					j++
+					if runes[j] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, errQuot
+			}
+			inWord, i = true, j
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			j, err := ansiCQuote(runes, i+2, &word)
+			if err != nil {
+				return nil, err
+			}
+			inWord, i = true, j
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// ansiCQuote decodes a $'...' string starting at runes[i] into w and
+// returns the index of the closing quote.
+func ansiCQuote(runes []rune, i int, w *strings.Builder) (int, error) {
+	escapes := map[rune]string{
+		'n': "\n", 't': "\t", 'r': "\r", 'a': "\a", 'b': "\b", 'f': "\f", 'v': "\v",
+		'e': "\x1b", '\\': "\\", '\'': "'", '"': "\"", '?': "?",
+	}
+
+	for ; i < len(runes); i++ {
+		r := runes[i]
+
+		if r == '\'' {
+			return i, nil
+		}
+
+		if r != '\\' || i+1 >= len(runes) {
+			w.WriteRune(r)
+			continue
+		}
+
+		i++
+		if e, ok := escapes[runes[i]]; ok {
+			w.WriteString(e)
+			continue
+		}
+
+		if runes[i] == 'x' {
+			j := i + 1
+			for j < len(runes) && j < i+3 && strings.ContainsRune("0123456789abcdefABCDEF", runes[j]) {
+				j++
+			}
+			if b, err := strconv.ParseUint(string(runes[i+1:j]), 16, 8); err == nil {
+				w.WriteByte(byte(b))
+				i = j - 1
+				continue
+			}
+		}
+
+		w.WriteRune('\\')
+		w.WriteRune(runes[i])
+	}
+
+	return i, errors.New("request: unterminated quote in curl command")
+}
@@ -0,0 +1,145 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestFromCurl(t *testing.T) {
+	tests := []struct {
+		command string
+		method  string
+		url     string
+		header  map[string]string
+		body    string
+	}{
+		{"curl https://api.test/users", "GET", "https://api.test/users", nil, ""},
+		{"curl api.test", "GET", "http://api.test", nil, ""},
+		{"curl -X DELETE https://api.test/users/1", "DELETE", "https://api.test/users/1", nil, ""},
+		{"curl -XPUT https://api.test", "PUT", "https://api.test", nil, ""},
+		{"curl -sXPOST https://api.test", "POST", "https://api.test", nil, ""},
+		{"curl -sX POST https://api.test", "POST", "https://api.test", nil, ""},
+		{"curl -sSLX PATCH https://api.test", "PATCH", "https://api.test", nil, ""},
+		{"curl -sHAccept:text/plain https://api.test", "GET", "https://api.test", map[string]string{"Accept": "text/plain"}, ""},
+		{"curl -I https://api.test", "HEAD", "https://api.test", nil, ""},
+		{"curl --head https://api.test", "HEAD", "https://api.test", nil, ""},
+		{"curl -sI https://api.test", "HEAD", "https://api.test", nil, ""},
+		{"curl -G -d a=1 -d b=2 https://api.test/s", "GET", "https://api.test/s?a=1&b=2", nil, ""},
+		{"curl -d a=1 -d b=2 https://api.test", "POST", "https://api.test", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, "a=1&b=2"},
+		{`curl -H 'Content-Type: application/json' --data-raw '{"a": 1}' https://api.test`, "POST", "https://api.test", map[string]string{"Content-Type": "application/json"}, `{"a": 1}`},
+		{"curl --data-urlencode 'q=a b' https://api.test", "POST", "https://api.test", nil, "q=a+b"},
+		{"curl -A agent -e https://ref.test --url https://api.test", "GET", "https://api.test", map[string]string{"User-Agent": "agent", "Referer": "https://ref.test"}, ""},
+		{"curl -u user:pass https://api.test", "GET", "https://api.test", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, ""},
+		{"curl -b 'a=1; b=2' https://api.test", "GET", "https://api.test", map[string]string{"Cookie": "a=1; b=2"}, ""},
+		{"curl -- -weird", "GET", "http://-weird", nil, ""},
+	}
+
+	for _, tt := range tests {
+		c, err := FromCurl(tt.command)
+		if err != nil {
+			t.Errorf("FromCurl(%q): %v", tt.command, err)
+			continue
+		}
+
+		req, err := c.Req()
+		if err != nil {
+			t.Errorf("FromCurl(%q).Req(): %v", tt.command, err)
+			continue
+		}
+
+		if req.Method != tt.method || req.URL.String() != tt.url {
+			t.Errorf("FromCurl(%q) = %s %s, want %s %s", tt.command, req.Method, req.URL, tt.method, tt.url)
+		}
+
+		for k, v := range tt.header {
+			if got := req.Header.Get(k); got != v {
+				t.Errorf("FromCurl(%q): %s = %q, want %q", tt.command, k, got, v)
+			}
+		}
+
+		var body string
+		if req.Body != nil {
+			b, _ := ioutil.ReadAll(req.Body)
+			body = string(b)
+		}
+		if body != tt.body {
+			t.Errorf("FromCurl(%q): body = %q, want %q", tt.command, body, tt.body)
+		}
+	}
+}
+
+func TestFromCurlErrors(t *testing.T) {
+	tests := []string{
+		"curl",
+		"curl -X",
+		"curl -sX",
+		"curl --insecure https://api.test",
+		"curl -sk https://api.test",
+		"curl https://a.test https://b.test",
+		"curl -d a=1 -F b=2 https://api.test",
+		"curl -m soon https://api.test",
+		"curl -b cookies.txt https://api.test",
+		"curl 'https://api.test",
+	}
+
+	for _, command := range tests {
+		if _, err := FromCurl(command); err == nil {
+			t.Errorf("FromCurl(%q) succeeded, want an error", command)
+		}
+	}
+}
+
+func TestCurl(t *testing.T) {
+	ua := Settings().UserAgent
+	defer Settings().SetUserAgent(ua)
+	Settings().SetUserAgent("")
+
+	tests := []struct {
+		client *Client
+		want   string
+	}{
+		{New().To("GET", "https://api.test/users?page=2"), "curl 'https://api.test/users?page=2'"},
+		{New().To("HEAD", "https://api.test"), "curl -I https://api.test"},
+		{New().To("DELETE", "https://api.test/users/1"), "curl -X DELETE https://api.test/users/1"},
+		{New().To("POST", "https://api.test").SendBody(map[string]int{"a": 1}), `curl -X POST https://api.test -H 'Content-Type: application/json' --data-binary '{"a":1}'`},
+		{New().To("POST", "https://api.test").AddField("q", "it's"), `curl -X POST https://api.test --data-raw q=it%27s`},
+		{New().To("POST", "https://api.test").SetMultipart().AddField("a", "1"), "curl -X POST https://api.test --form-string a=1"},
+		{New().To("GET", "https://api.test").SetHeader("X-Token", "a b").SetAuth("u", "p"), "curl https://api.test -H 'X-Token: a b' -u u:p"},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.client.Curl()
+		if err != nil || got != tt.want {
+			t.Errorf("Curl() = %q, %v, want %q", got, err, tt.want)
+		}
+	}
+}
+
+// The command returned by Curl gives back the same request.
+func TestCurlRoundTrip(t *testing.T) {
+	c := New().To("PUT", "https://api.test/items/1?x=1").
+		SetHeader("Accept", "application/json").
+		SendBody(`{"name":"it's"}`)
+
+	command, err := c.Curl()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := FromCurl(command)
+	if err != nil {
+		t.Fatalf("FromCurl(%q): %v", command, err)
+	}
+
+	req, err := back.Req()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := ioutil.ReadAll(req.Body)
+	if req.Method != "PUT" || req.URL.String() != "https://api.test/items/1?x=1" || string(b) != `{"name":"it's"}` ||
+		!strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") || req.Header.Get("Accept") != "application/json" {
+		t.Errorf("FromCurl(%q) = %s %s %v %q", command, req.Method, req.URL, req.Header, b)
+	}
+}
@@ -0,0 +1,638 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver resolves host names to IP addresses, *net.Resolver implements
+// it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// ttlResolver is implemented by the resolvers which know the TTL of the
+// records they return.
+type ttlResolver interface {
+	lookupTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error)
+}
+
+// IPPreference controls the address families dialed when a host resolves
+// to both IPv4 and IPv6 addresses.
+type IPPreference int
+
+// IP preferences.
+const (
+	// IPDual dials the addresses in the order of the resolver, racing the
+	// other family after the fallback delay (happy eyeballs, RFC 8305).
+	IPDual IPPreference = iota
+	// PreferIPv4 dials the IPv4 addresses first, racing the IPv6 ones after
+	// the fallback delay.
+	PreferIPv4
+	// PreferIPv6 dials the IPv6 addresses first, racing the IPv4 ones after
+	// the fallback delay.
+	PreferIPv6
+	// IPv4Only dials the IPv4 addresses only.
+	IPv4Only
+	// IPv6Only dials the IPv6 addresses only.
+	IPv6Only
+)
+
+// SetHostAddrs resolves host to addrs for the requests of the session,
+// like curl --resolve, leaving the URL, the Host header and the TLS server
+// name unchanged. host may be "name" or "name:port" to override a single
+// port, addrs are IP addresses.
+func (s *Session) SetHostAddrs(host string, addrs ...string) *Session {
+	ips := make([]net.IPAddr, 0, len(addrs))
+
+	for _, a := range addrs {
+		ip := net.ParseIP(strings.Trim(a, "[]"))
+		if ip == nil {
+			s.err = fmt.Errorf("request: invalid IP address %q for %s", a, host)
+			return s
+		}
+		ips = append(ips, net.IPAddr{IP: ip})
+	}
+
+	s.transports.update(func(t *sessionTransport) {
+		// 复制一份，已派生的 transport 可能正在读取旧的表
+		hosts := make(map[string][]net.IPAddr, len(t.hosts)+1)
+		for k, v := range t.hosts {
+			hosts[k] = v
+		}
+		hosts[strings.ToLower(host)] = ips
+		t.hosts = hosts
+	})
+	return s
+}
+
+// SetResolver sets the Resolver of the host names of the session, such as
+// a CachingResolver or a DoHResolver.
+func (s *Session) SetResolver(r Resolver) *Session {
+	s.transports.update(func(t *sessionTransport) { t.resolver = r })
+	return s
+}
+
+// SetIPPreference sets the address families dialed by the session and the
+// delay before racing the fallback family, 300ms when fallbackDelay is 0.
+// A negative delay dials the addresses one after the other.
+func (s *Session) SetIPPreference(p IPPreference, fallbackDelay time.Duration) *Session {
+	s.transports.update(func(t *sessionTransport) {
+		t.preference, t.fallbackDelay, t.preferenceSet = p, fallbackDelay, true
+	})
+	return s
+}
+
+// resolvingDialer resolves the host names itself before dialing.
+type resolvingDialer struct {
+	dialer        Dialer
+	hosts         map[string][]net.IPAddr
+	resolver      Resolver
+	preference    IPPreference
+	fallbackDelay time.Duration
+}
+
+func (d *resolvingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := d.lookup(ctx, host, port)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	primaries, fallbacks := d.partition(network, ips)
+	if len(primaries) == 0 {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: &net.AddrError{Err: "no suitable address found", Addr: host}}
+	}
+
+	return d.dialParallel(ctx, network, port, primaries, fallbacks)
+}
+
+func (d *resolvingDialer) lookup(ctx context.Context, host, port string) ([]net.IPAddr, error) {
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if ips, ok := d.hosts[net.JoinHostPort(name, port)]; ok {
+		return ips, nil
+	}
+
+	if ips, ok := d.hosts[name]; ok {
+		return ips, nil
+	}
+
+	if d.resolver == nil {
+		return net.DefaultResolver.LookupIPAddr(ctx, host)
+	}
+
+	return d.resolver.LookupIPAddr(ctx, host)
+}
+
+// partition splits the addresses into the primary family, dialed first,
+// and the fallback one.
+func (d *resolvingDialer) partition(network string, ips []net.IPAddr) (primaries, fallbacks []net.IPAddr) {
+	v4 := func(ip net.IPAddr) bool { return ip.IP.To4() != nil }
+
+	var keep func(ip net.IPAddr) bool
+	switch {
+	case network == "tcp4" || d.preference == IPv4Only:
+		keep = v4
+	case network == "tcp6" || d.preference == IPv6Only:
+		keep = func(ip net.IPAddr) bool { return !v4(ip) }
+	}
+
+	if keep != nil {
+		for _, ip := range ips {
+			if keep(ip) {
+				primaries = append(primaries, ip)
+			}
+		}
+		return primaries, nil
+	}
+
+	if len(ips) == 0 {
+		return nil, nil
+	}
+
+	primaryV4 := v4(ips[0])
+	switch d.preference {
+	case PreferIPv4:
+		primaryV4 = true
+	case PreferIPv6:
+		primaryV4 = false
+	}
+
+	for _, ip := range ips {
+		if v4(ip) == primaryV4 {
+			primaries = append(primaries, ip)
+		} else {
+			fallbacks = append(fallbacks, ip)
+		}
+	}
+
+	if len(primaries) == 0 {
+		return fallbacks, nil
+	}
+
+	return primaries, fallbacks
+}
+
+// dialParallel dials the primaries one after the other and races the
+// fallbacks after the fallback delay, the first connection wins.
+func (d *resolvingDialer) dialParallel(ctx context.Context, network, port string, primaries, fallbacks []net.IPAddr) (net.Conn, error) {
+	if len(fallbacks) == 0 || d.fallbackDelay < 0 {
+		return d.dialSerial(ctx, network, port, append(primaries, fallbacks...))
+	}
+
+	delay := d.fallbackDelay
+	if delay == 0 {
+		delay = 300 * time.Millisecond
+	}
+
+	type result struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result)
+	race := func(primary bool, ips []net.IPAddr) {
+		conn, err := d.dialSerial(ctx, network, port, ips)
+		select {
+		case results <- result{conn, err, primary}:
+		case <-ctx.Done():
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	}
+
+	go race(true, primaries)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var firstErr error
+	pending, fallbackStarted := 1, false
+
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				go race(false, fallbacks)
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.conn, nil
+			}
+
+			if firstErr == nil || r.primary {
+				firstErr = r.err
+			}
+
+			// 主地址族失败时立即尝试备用地址族
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				timer.Stop()
+				go race(false, fallbacks)
+			}
+
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+func (d *resolvingDialer) dialSerial(ctx context.Context, network, port string, ips []net.IPAddr) (net.Conn, error) {
+	if network == "tcp4" || network == "tcp6" {
+		network = "tcp"
+	}
+
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, firstErr
+}
+
+// CachingResolver caches the addresses returned by another Resolver, so
+// loops sending many requests do not look up the same hosts again and
+// again. Concurrent lookups of a host share a single query, failed lookups
+// are not cached.
+type CachingResolver struct {
+	resolver Resolver
+	ttl      time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]cachedAddrs
+	inflight map[string]*lookupCall
+}
+
+type cachedAddrs struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+type lookupCall struct {
+	done  chan struct{}
+	addrs []net.IPAddr
+	err   error
+}
+
+// NewCachingResolver returns a CachingResolver keeping the addresses
+// returned by r, net.DefaultResolver when nil, for ttl. The addresses of a
+// DoHResolver are kept for the TTL of their records when it is shorter.
+func NewCachingResolver(r Resolver, ttl time.Duration) *CachingResolver {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
+	return &CachingResolver{
+		resolver: r,
+		ttl:      ttl,
+		entries:  make(map[string]cachedAddrs),
+		inflight: make(map[string]*lookupCall),
+	}
+}
+
+// LookupIPAddr returns the cached addresses of host, looking them up when
+// they expired.
+func (r *CachingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	key := strings.ToLower(host)
+
+	r.mu.Lock()
+	if e, ok := r.entries[key]; ok && time.Now().Before(e.expires) {
+		r.mu.Unlock()
+		return e.addrs, nil
+	}
+
+	call, ok := r.inflight[key]
+	if !ok {
+		call = &lookupCall{done: make(chan struct{})}
+		r.inflight[key] = call
+		go r.resolve(key, host, call)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.addrs, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolve looks host up in the background, so a canceled caller does not
+// fail the other ones.
+func (r *CachingResolver) resolve(key, host string, call *lookupCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ttl := r.ttl
+	if tr, ok := r.resolver.(ttlResolver); ok {
+		var recordTTL time.Duration
+		call.addrs, recordTTL, call.err = tr.lookupTTL(ctx, host)
+		if recordTTL < ttl {
+			ttl = recordTTL
+		}
+	} else {
+		call.addrs, call.err = r.resolver.LookupIPAddr(ctx, host)
+	}
+
+	r.mu.Lock()
+	delete(r.inflight, key)
+	if call.err == nil && ttl > 0 {
+		r.entries[key] = cachedAddrs{addrs: call.addrs, expires: time.Now().Add(ttl)}
+	}
+	r.mu.Unlock()
+
+	close(call.done)
+}
+
+// Flush removes every cached address.
+func (r *CachingResolver) Flush() {
+	r.mu.Lock()
+	r.entries = make(map[string]cachedAddrs)
+	r.mu.Unlock()
+}
+
+// DoHResolver resolves host names with DNS over HTTPS (RFC 8484), sending
+// its queries with a session of this package.
+type DoHResolver struct {
+	endpoint string
+	session  *Session
+}
+
+// NewDoHResolver returns a DoHResolver sending its queries to endpoint,
+// such as "https://cloudflare-dns.com/dns-query". The endpoint is reached
+// with a session of its own, which must not use the resolver itself.
+func NewDoHResolver(endpoint string) *DoHResolver {
+	return &DoHResolver{endpoint: endpoint, session: NewSession()}
+}
+
+// SetSession sets the session sending the queries, to configure its
+// transport, timeout or logger.
+func (r *DoHResolver) SetSession(s *Session) *DoHResolver {
+	r.session = s
+	return r
+}
+
+// LookupIPAddr returns the IPv4 and IPv6 addresses of host.
+func (r *DoHResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, _, err := r.lookupTTL(ctx, host)
+	return addrs, err
+}
+
+const (
+	dnsTypeA     = 1
+	dnsTypeCNAME = 5
+	dnsTypeAAAA  = 28
+)
+
+func (r *DoHResolver) lookupTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	type answer struct {
+		addrs []net.IPAddr
+		ttl   time.Duration
+		err   error
+	}
+
+	ch := make(chan answer, 2)
+	for _, qtype := range []uint16{dnsTypeA, dnsTypeAAAA} {
+		go func(qtype uint16) {
+			var a answer
+			a.addrs, a.ttl, a.err = r.query(ctx, host, qtype)
+			ch <- a
+		}(qtype)
+	}
+
+	var (
+		addrs    []net.IPAddr
+		ttl      time.Duration
+		firstErr error
+	)
+
+	for i := 0; i < 2; i++ {
+		a := <-ch
+		if a.err != nil {
+			if firstErr == nil {
+				firstErr = a.err
+			}
+			continue
+		}
+
+		if len(a.addrs) > 0 && (ttl == 0 || a.ttl < ttl) {
+			ttl = a.ttl
+		}
+		addrs = append(addrs, a.addrs...)
+	}
+
+	if len(addrs) == 0 {
+		if firstErr == nil {
+			firstErr = &net.DNSError{Err: "no such host", Name: host, Server: r.endpoint, IsNotFound: true}
+		}
+		return nil, 0, firstErr
+	}
+
+	return addrs, ttl, nil
+}
+
+// query sends a single question and returns the addresses of the answer.
+func (r *DoHResolver) query(ctx context.Context, host string, qtype uint16) ([]net.IPAddr, time.Duration, error) {
+	msg, err := dnsQuery(host, qtype)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res, err := r.session.To(http.MethodGet, r.endpoint).SetContext(ctx).
+		AddQuery("dns", base64.RawURLEncoding.EncodeToString(msg)).
+		SetHeader("Accept", "application/dns-message").
+		Execute()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b, err := res.Content()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !res.OK() {
+		return nil, 0, &net.DNSError{Err: "DNS over HTTPS answered " + res.Status, Name: host, Server: r.endpoint}
+	}
+
+	addrs, ttl, err := parseDNSAnswer(b, qtype)
+	if err != nil {
+		if de, ok := err.(*net.DNSError); ok {
+			de.Name, de.Server = host, r.endpoint
+		}
+		return nil, 0, err
+	}
+
+	return addrs, ttl, nil
+}
+
+// dnsQuery encodes a recursive query for host in the DNS wire format.
+func dnsQuery(host string, qtype uint16) ([]byte, error) {
+	// ID 为 0 以便 HTTP 缓存 (RFC 8484 4.1)
+	msg := []byte{0, 0, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, &net.DNSError{Err: "invalid host name", Name: host}
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // IN
+
+	return msg, nil
+}
+
+var errDNSMessage = errors.New("request: malformed DNS message")
+
+// parseDNSAnswer returns the addresses of type qtype in a DNS response and
+// their smallest TTL, the one of the CNAME records leading to them included.
+func parseDNSAnswer(msg []byte, qtype uint16) ([]net.IPAddr, time.Duration, error) {
+	if len(msg) < 12 {
+		return nil, 0, errDNSMessage
+	}
+
+	switch rcode := msg[3] & 0x0f; rcode {
+	case 0:
+	case 3:
+		return nil, 0, &net.DNSError{Err: "no such host", IsNotFound: true}
+	default:
+		return nil, 0, &net.DNSError{Err: fmt.Sprintf("server failure, rcode %d", rcode), IsTemporary: rcode == 2}
+	}
+
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+
+	for i := 0; i < qdcount; i++ {
+		var err error
+		if off, err = skipDNSName(msg, off); err != nil {
+			return nil, 0, err
+		}
+
+		if off += 4; off > len(msg) {
+			return nil, 0, errDNSMessage
+		}
+	}
+
+	var (
+		addrs    []net.IPAddr
+		ttl      time.Duration
+		cname    bool
+		cnameTTL time.Duration
+	)
+
+	for i := 0; i < ancount; i++ {
+		var err error
+		if off, err = skipDNSName(msg, off); err != nil {
+			return nil, 0, err
+		}
+
+		if off+10 > len(msg) {
+			return nil, 0, errDNSMessage
+		}
+
+		typ := binary.BigEndian.Uint16(msg[off:])
+		rrTTL := time.Duration(binary.BigEndian.Uint32(msg[off+4:])) * time.Second
+		length := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+
+		if off+length > len(msg) {
+			return nil, 0, errDNSMessage
+		}
+
+		data := msg[off : off+length]
+		off += length
+
+		switch {
+		case typ == dnsTypeCNAME:
+			// CNAME 的目标地址记录也在应答中，只取它的 TTL
+			if !cname || rrTTL < cnameTTL {
+				cname, cnameTTL = true, rrTTL
+			}
+			continue
+		case typ != qtype:
+			continue
+		case (typ == dnsTypeA && length != net.IPv4len) || (typ == dnsTypeAAAA && length != net.IPv6len):
+			return nil, 0, errDNSMessage
+		}
+
+		addrs = append(addrs, net.IPAddr{IP: net.IP(append([]byte(nil), data...))})
+		if len(addrs) == 1 || rrTTL < ttl {
+			ttl = rrTTL
+		}
+	}
+
+	// 别名过期后地址也不再可信
+	if len(addrs) > 0 && cname && cnameTTL < ttl {
+		ttl = cnameTTL
+	}
+
+	return addrs, ttl, nil
+}
+
+// skipDNSName returns the offset following the name at off.
+func skipDNSName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errDNSMessage
+		}
+
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			return off + 1, nil
+		case n&0xc0 == 0xc0:
+			// 压缩指针，名字到此结束
+			if off+2 > len(msg) {
+				return 0, errDNSMessage
+			}
+			return off + 2, nil
+		case n&0xc0 != 0:
+			return 0, errDNSMessage
+		}
+
+		off += 1 + n
+	}
+}
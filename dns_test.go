@@ -0,0 +1,408 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lets-go-go/httpclient/mock"
+)
+
+// namePtr is a compression pointer to the name of the question.
+var namePtr = []byte{0xc0, 12}
+
+// dnsName encodes name without compression.
+func dnsName(name string) []byte {
+	var b []byte
+	for _, label := range bytes.Split([]byte(name), []byte(".")) {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// dnsRR encodes a resource record of class IN.
+func dnsRR(name []byte, typ uint16, ttl uint32, data []byte) []byte {
+	b := append([]byte(nil), name...)
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint16(b, 1)
+	b = binary.BigEndian.AppendUint32(b, ttl)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+// dnsResponse answers the query for host with rcode and the records.
+func dnsResponse(t testing.TB, host string, qtype uint16, rcode byte, records ...[]byte) []byte {
+	t.Helper()
+
+	msg, err := dnsQuery(host, qtype)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg[2] |= 0x80
+	msg[3] = 0x80 | rcode
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(records)))
+
+	for _, rr := range records {
+		msg = append(msg, rr...)
+	}
+	return msg
+}
+
+func TestDNSQuery(t *testing.T) {
+	want := []byte{
+		0, 0, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0,
+		3, 'w', 'w', 'w', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0, 28, 0, 1,
+	}
+
+	long := make([]byte, 64)
+	for i := range long {
+		long[i] = 'a'
+	}
+
+	tests := []struct {
+		host string
+		want []byte
+	}{
+		{"www.example.com", want},
+		{"www.example.com.", want},
+		{"www..example.com", nil},
+		{"", nil},
+		{string(long) + ".com", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := dnsQuery(tt.host, dnsTypeAAAA)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("dnsQuery(%q) = %v, want an error", tt.host, got)
+			}
+			continue
+		}
+
+		if err != nil || !bytes.Equal(got, tt.want) {
+			t.Errorf("dnsQuery(%q) = %v, %v, want %v", tt.host, got, err, tt.want)
+		}
+	}
+}
+
+func TestSkipDNSName(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  []byte
+		want int
+		err  bool
+	}{
+		{"root", []byte{0}, 1, false},
+		{"labels", dnsName("a.example.com"), 15, false},
+		{"pointer", []byte{0xc0, 12}, 2, false},
+		{"labels then pointer", []byte{3, 'w', 'w', 'w', 0xc0, 12}, 6, false},
+		{"empty", nil, 0, true},
+		{"truncated label", []byte{7, 'e', 'x'}, 0, true},
+		{"missing terminator", []byte{3, 'c', 'o', 'm'}, 0, true},
+		{"truncated pointer", []byte{3, 'w', 'w', 'w', 0xc0}, 0, true},
+		{"reserved label type", []byte{0x40, 0}, 0, true},
+		{"extended label type", []byte{0x80, 0}, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := skipDNSName(tt.msg, 0)
+		switch {
+		case tt.err && err == nil:
+			t.Errorf("%s: skipDNSName = %d, want an error", tt.name, got)
+		case !tt.err && (err != nil || got != tt.want):
+			t.Errorf("%s: skipDNSName = %d, %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseDNSAnswer(t *testing.T) {
+	const host = "www.example.com"
+
+	ipv4 := []byte{192, 0, 2, 1}
+	ipv4b := []byte{192, 0, 2, 2}
+	ipv6 := net.ParseIP("2001:db8::1").To16()
+	target := dnsName("edge.example.net")
+
+	ok := func(records ...[]byte) []byte { return dnsResponse(t, host, dnsTypeA, 0, records...) }
+	truncate := func(msg []byte, n int) []byte { return msg[:len(msg)-n] }
+	answers := func(msg []byte, n uint16) []byte {
+		binary.BigEndian.PutUint16(msg[6:], n)
+		return msg
+	}
+
+	tests := []struct {
+		name      string
+		msg       []byte
+		qtype     uint16
+		want      []net.IP
+		ttl       time.Duration
+		err       error
+		notFound  bool
+		temporary bool
+	}{
+		{
+			name:  "single address",
+			msg:   ok(dnsRR(namePtr, dnsTypeA, 300, ipv4)),
+			qtype: dnsTypeA,
+			want:  []net.IP{ipv4},
+			ttl:   300 * time.Second,
+		},
+		{
+			name:  "smallest ttl",
+			msg:   ok(dnsRR(namePtr, dnsTypeA, 300, ipv4), dnsRR(namePtr, dnsTypeA, 60, ipv4b)),
+			qtype: dnsTypeA,
+			want:  []net.IP{ipv4, ipv4b},
+			ttl:   60 * time.Second,
+		},
+		{
+			name:  "uncompressed name",
+			msg:   ok(dnsRR(dnsName(host), dnsTypeA, 300, ipv4)),
+			qtype: dnsTypeA,
+			want:  []net.IP{ipv4},
+			ttl:   300 * time.Second,
+		},
+		{
+			name:  "labels then pointer",
+			msg:   ok(dnsRR([]byte{3, 'w', 'w', 'w', 0xc0, 16}, dnsTypeA, 300, ipv4)),
+			qtype: dnsTypeA,
+			want:  []net.IP{ipv4},
+			ttl:   300 * time.Second,
+		},
+		{
+			name:  "ipv6",
+			msg:   dnsResponse(t, host, dnsTypeAAAA, 0, dnsRR(namePtr, dnsTypeAAAA, 120, ipv6)),
+			qtype: dnsTypeAAAA,
+			want:  []net.IP{ipv6},
+			ttl:   120 * time.Second,
+		},
+		{
+			name: "cname chain",
+			msg: ok(
+				dnsRR(namePtr, dnsTypeCNAME, 3600, target),
+				dnsRR(target, dnsTypeCNAME, 30, dnsName("pop.example.net")),
+				dnsRR(dnsName("pop.example.net"), dnsTypeA, 300, ipv4),
+			),
+			qtype: dnsTypeA,
+			want:  []net.IP{ipv4},
+			ttl:   30 * time.Second,
+		},
+		{
+			name:  "cname without address",
+			msg:   ok(dnsRR(namePtr, dnsTypeCNAME, 30, target)),
+			qtype: dnsTypeA,
+		},
+		{
+			name:  "other type skipped",
+			msg:   dnsResponse(t, host, dnsTypeAAAA, 0, dnsRR(namePtr, dnsTypeA, 300, ipv4)),
+			qtype: dnsTypeAAAA,
+		},
+		{
+			name:  "no answer",
+			msg:   ok(),
+			qtype: dnsTypeA,
+		},
+		{
+			name:     "nxdomain",
+			msg:      dnsResponse(t, host, dnsTypeA, 3),
+			qtype:    dnsTypeA,
+			notFound: true,
+		},
+		{
+			name:      "servfail",
+			msg:       dnsResponse(t, host, dnsTypeA, 2),
+			qtype:     dnsTypeA,
+			temporary: true,
+		},
+		{
+			name:  "refused",
+			msg:   dnsResponse(t, host, dnsTypeA, 5),
+			qtype: dnsTypeA,
+			err:   &net.DNSError{},
+		},
+		{
+			name:  "truncated header",
+			msg:   ok()[:11],
+			qtype: dnsTypeA,
+			err:   errDNSMessage,
+		},
+		{
+			name:  "truncated question",
+			msg:   truncate(ok(), 2),
+			qtype: dnsTypeA,
+			err:   errDNSMessage,
+		},
+		{
+			name:  "missing answer",
+			msg:   truncate(ok(dnsRR(namePtr, dnsTypeA, 300, ipv4)), 16),
+			qtype: dnsTypeA,
+			err:   errDNSMessage,
+		},
+		{
+			name:  "truncated record header",
+			msg:   truncate(ok(dnsRR(namePtr, dnsTypeA, 300, ipv4)), 8),
+			qtype: dnsTypeA,
+			err:   errDNSMessage,
+		},
+		{
+			name:  "rdata past the end",
+			msg:   truncate(ok(dnsRR(namePtr, dnsTypeA, 300, ipv4)), 1),
+			qtype: dnsTypeA,
+			err:   errDNSMessage,
+		},
+		{
+			name:  "short address",
+			msg:   ok(dnsRR(namePtr, dnsTypeA, 300, ipv4[:3])),
+			qtype: dnsTypeA,
+			err:   errDNSMessage,
+		},
+		{
+			name:  "long address",
+			msg:   dnsResponse(t, host, dnsTypeAAAA, 0, dnsRR(namePtr, dnsTypeAAAA, 300, append(ipv6, 0))),
+			qtype: dnsTypeAAAA,
+			err:   errDNSMessage,
+		},
+		{
+			name:  "truncated pointer",
+			msg:   answers(append(ok(), 0xc0), 1),
+			qtype: dnsTypeA,
+			err:   errDNSMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		addrs, ttl, err := parseDNSAnswer(tt.msg, tt.qtype)
+
+		var de *net.DNSError
+		switch {
+		case tt.notFound || tt.temporary:
+			if !errors.As(err, &de) || de.IsNotFound != tt.notFound || de.IsTemporary != tt.temporary {
+				t.Errorf("%s: error = %#v, want not found %v, temporary %v", tt.name, err, tt.notFound, tt.temporary)
+			}
+			continue
+		case tt.err == errDNSMessage:
+			if err != errDNSMessage {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, errDNSMessage)
+			}
+			continue
+		case tt.err != nil:
+			if !errors.As(err, &de) || de.IsNotFound || de.IsTemporary {
+				t.Errorf("%s: error = %#v, want a permanent *net.DNSError", tt.name, err)
+			}
+			continue
+		case err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		if len(addrs) != len(tt.want) {
+			t.Errorf("%s: addresses = %v, want %v", tt.name, addrs, tt.want)
+			continue
+		}
+		for i, a := range addrs {
+			if !a.IP.Equal(tt.want[i]) {
+				t.Errorf("%s: address %d = %v, want %v", tt.name, i, a.IP, tt.want[i])
+			}
+		}
+
+		if ttl != tt.ttl {
+			t.Errorf("%s: ttl = %v, want %v", tt.name, ttl, tt.ttl)
+		}
+	}
+}
+
+func TestDoHResolver(t *testing.T) {
+	const host = "api.example.com"
+
+	tests := []struct {
+		name     string
+		rcode    byte
+		status   int
+		want     []string
+		ttl      time.Duration
+		notFound bool
+	}{
+		{name: "both families", want: []string{"192.0.2.1", "2001:db8::1"}, ttl: 60 * time.Second},
+		{name: "nxdomain", rcode: 3, notFound: true},
+		{name: "http error", status: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		mt := mock.NewTransport()
+		mt.On("GET", "https://dns.test/dns-query").ReplyFunc(func(req *http.Request) (*http.Response, error) {
+			q, err := base64.RawURLEncoding.DecodeString(req.URL.Query().Get("dns"))
+			if err != nil {
+				return nil, err
+			}
+
+			qtype := binary.BigEndian.Uint16(q[len(q)-4:])
+
+			var records [][]byte
+			if tt.rcode == 0 {
+				if qtype == dnsTypeA {
+					records = append(records, dnsRR(namePtr, dnsTypeA, 300, net.ParseIP("192.0.2.1").To4()))
+				} else {
+					records = append(records, dnsRR(namePtr, dnsTypeAAAA, 60, net.ParseIP("2001:db8::1").To16()))
+				}
+			}
+
+			status := tt.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			body := dnsResponse(t, host, qtype, tt.rcode, records...)
+			return &http.Response{
+				StatusCode:    status,
+				Status:        http.StatusText(status),
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        http.Header{"Content-Type": {"application/dns-message"}},
+				Body:          io.NopCloser(bytes.NewReader(body)),
+				ContentLength: int64(len(body)),
+				Request:       req,
+			}, nil
+		})
+
+		r := NewDoHResolver("https://dns.test/dns-query").SetSession(NewSession().SetTransport(mt))
+		addrs, ttl, err := r.lookupTTL(context.Background(), host)
+
+		if tt.want == nil {
+			var de *net.DNSError
+			if !errors.As(err, &de) || de.IsNotFound != tt.notFound || de.Name != host {
+				t.Errorf("%s: error = %#v, want a *net.DNSError for %s, not found %v", tt.name, err, host, tt.notFound)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		got := make(map[string]bool)
+		for _, a := range addrs {
+			got[a.IP.String()] = true
+		}
+		for _, w := range tt.want {
+			if !got[w] {
+				t.Errorf("%s: addresses = %v, want %v", tt.name, addrs, tt.want)
+				break
+			}
+		}
+
+		if ttl != tt.ttl {
+			t.Errorf("%s: ttl = %v, want %v", tt.name, ttl, tt.ttl)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package httpclient
+
+import (
+	"expvar"
+	"time"
+)
+
+// ExpvarMetrics is a Metrics publishing the measurements with the expvar
+// package, so they are served on /debug/vars. The published variable is a
+// map of maps keyed by "METHOD host status", for example
+//
+//	"httpclient": {"requests": {"GET api.example.com 2xx": 12}, ...}
+type ExpvarMetrics struct {
+	requests  *expvar.Map
+	durations *expvar.Map // 延迟总和，单位秒
+	inFlight  *expvar.Map
+	sent      *expvar.Map
+	received  *expvar.Map
+	retries   *expvar.Map
+}
+
+// NewExpvarMetrics returns an ExpvarMetrics published under name, which
+// defaults to "httpclient". The variable is reused when name was already
+// published by a previous call.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	if name == "" {
+		name = "httpclient"
+	}
+
+	root, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		root = expvar.NewMap(name)
+	}
+
+	child := func(key string) *expvar.Map {
+		if m, ok := root.Get(key).(*expvar.Map); ok {
+			return m
+		}
+
+		m := new(expvar.Map).Init()
+		root.Set(key, m)
+		return m
+	}
+
+	return &ExpvarMetrics{
+		requests:  child("requests"),
+		durations: child("duration_seconds"),
+		inFlight:  child("in_flight"),
+		sent:      child("bytes_sent"),
+		received:  child("bytes_received"),
+		retries:   child("retries"),
+	}
+}
+
+func expvarKey(l Labels) string {
+	if l.Status == "" {
+		return l.Method + " " + l.Host
+	}
+	return l.Method + " " + l.Host + " " + l.Status
+}
+
+// RequestStarted implements Metrics.
+func (e *ExpvarMetrics) RequestStarted(l Labels) {
+	e.inFlight.Add(expvarKey(l), 1)
+}
+
+// RequestDone implements Metrics.
+func (e *ExpvarMetrics) RequestDone(l Labels, d time.Duration, bytesSent int64) {
+	e.inFlight.Add(expvarKey(Labels{Method: l.Method, Host: l.Host}), -1)
+
+	key := expvarKey(l)
+	e.requests.Add(key, 1)
+	e.durations.AddFloat(key, d.Seconds())
+	e.sent.Add(key, bytesSent)
+}
+
+// ResponseRead implements Metrics.
+func (e *ExpvarMetrics) ResponseRead(l Labels, bytesReceived int64) {
+	e.received.Add(expvarKey(l), bytesReceived)
+}
+
+// Retried implements Metrics.
+func (e *ExpvarMetrics) Retried(l Labels) {
+	e.retries.Add(expvarKey(l), 1)
+}
@@ -1,3 +1,8 @@
 module github.com/lets-go-go/httpclient
 
-require golang.org/x/net v0.0.0-20181113165502-88d92db4c548
+go 1.24
+
+require (
+	golang.org/x/net v0.0.0-20181113165502-88d92db4c548
+	gopkg.in/yaml.v3 v3.0.1
+)
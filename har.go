@@ -0,0 +1,402 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR is an HTTP Archive 1.2 document.
+// See http://www.softwareishard.com/blog/har-12-spec/.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root object of a HAR document.
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+// HARCreator describes the application which created the log.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is an exchange recorded in a HAR log.
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+}
+
+// HARRequest is a request in a HAR log.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse is a response in a HAR log.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue is a header or a query parameter.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARCookie is a cookie sent or received.
+type HARCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+// HARPostData is the body of a request, before its compression by
+// SetCompression. A binary body is base64 encoded and Encoding is "base64".
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARContent is the body of a response.
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings are the durations of the phases of an exchange in
+// milliseconds, -1 means the phase does not apply.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HARRecorder captures every exchange of the sessions it is attached to
+// with Session.SetHARRecorder, with an entry for each hop of a redirect.
+// The response bodies are captured as they are read by the caller.
+type HARRecorder struct {
+	mu      sync.Mutex
+	records []*harRecord
+}
+
+type harRecord struct {
+	req     *http.Request
+	reqBody []byte
+	res     *http.Response
+	body    *lockedBuffer
+	trace   *clientTrace
+}
+
+// NewHARRecorder returns a new instance of HARRecorder.
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// HAR returns the HAR document of the exchanges recorded so far.
+func (h *HARRecorder) HAR() *HAR {
+	h.mu.Lock()
+	records := append([]*harRecord(nil), h.records...)
+	h.mu.Unlock()
+
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "lets-go-go httpclient", Version: "1.0"},
+		Entries: make([]*HAREntry, 0, len(records)),
+	}}
+
+	for _, r := range records {
+		har.Log.Entries = append(har.Log.Entries, r.entry())
+	}
+
+	return har
+}
+
+// WriteTo writes the HAR document as JSON to w.
+func (h *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	b, err := json.MarshalIndent(h.HAR(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// WriteFile writes the HAR document to the file at path.
+func (h *HARRecorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err = h.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Reset discards the recorded exchanges.
+func (h *HARRecorder) Reset() {
+	h.mu.Lock()
+	h.records = nil
+	h.mu.Unlock()
+}
+
+// record adds the exchange, the returned writer captures the response body.
+func (h *HARRecorder) record(req *http.Request, res *http.Response, trace *clientTrace) io.Writer {
+	r := &harRecord{req: req, res: res, body: new(lockedBuffer), trace: trace}
+
+	getBody := UncompressedBody(req)
+	if getBody == nil {
+		getBody = req.GetBody
+	}
+
+	// 重定向为 GET 的请求不再带请求体
+	if getBody != nil && req.Body != nil && req.Body != http.NoBody {
+		if body, err := getBody(); err == nil {
+			r.reqBody, _ = ioutil.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	h.mu.Lock()
+	h.records = append(h.records, r)
+	h.mu.Unlock()
+
+	return r.body
+}
+
+// harTransport records every exchange sent through rt in h, including the
+// hops of redirects which Execute does not see.
+type harTransport struct {
+	rt http.RoundTripper
+	h  *HARRecorder
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.rt
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	trace := &clientTrace{start: time.Now(), hop: true}
+	res, err := rt.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace())))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Body == nil {
+		res.Body = http.NoBody
+	}
+	res.Body = &traceBody{ReadCloser: res.Body, trace: trace, tee: t.h.record(req, res, trace)}
+
+	return res, nil
+}
+
+func (r *harRecord) entry() *HAREntry {
+	t := r.trace.snapshot()
+	body := r.body.Bytes()
+
+	e := &HAREntry{
+		StartedDateTime: t.start.Format(time.RFC3339Nano),
+		Request: HARRequest{
+			Method:      r.req.Method,
+			URL:         r.req.URL.String(),
+			HTTPVersion: r.req.Proto,
+			Cookies:     harCookies(r.req.Cookies()),
+			Headers:     harHeaders(r.req.Header),
+			QueryString: []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(r.reqBody),
+		},
+		Response: HARResponse{
+			Status:      r.res.StatusCode,
+			StatusText:  http.StatusText(r.res.StatusCode),
+			HTTPVersion: r.res.Proto,
+			Cookies:     harCookies(r.res.Cookies()),
+			Headers:     harHeaders(r.res.Header),
+			Content: HARContent{
+				Size:     len(body),
+				MimeType: r.res.Header.Get("Content-Type"),
+			},
+			RedirectURL: r.res.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(body),
+		},
+		Timings: harTimings(&t),
+	}
+
+	for k, vs := range r.req.URL.Query() {
+		for _, v := range vs {
+			e.Request.QueryString = append(e.Request.QueryString, HARNameValue{Name: k, Value: v})
+		}
+	}
+
+	if len(r.reqBody) > 0 {
+		e.Request.PostData = &HARPostData{
+			MimeType: r.req.Header.Get("Content-Type"),
+		}
+
+		// 调用方自行编码的请求体无法还原
+		if utf8.Valid(r.reqBody) {
+			e.Request.PostData.Text = string(r.reqBody)
+		} else {
+			e.Request.PostData.Text = base64.StdEncoding.EncodeToString(r.reqBody)
+			e.Request.PostData.Encoding = "base64"
+		}
+	}
+
+	if utf8.Valid(body) {
+		e.Response.Content.Text = string(body)
+	} else {
+		e.Response.Content.Text = base64.StdEncoding.EncodeToString(body)
+		e.Response.Content.Encoding = "base64"
+	}
+
+	if host, port, err := net.SplitHostPort(t.remoteAddr); err == nil {
+		e.ServerIPAddress, e.Connection = host, port
+	}
+
+	for _, d := range []float64{e.Timings.Blocked, e.Timings.DNS, e.Timings.Connect, e.Timings.Send, e.Timings.Wait, e.Timings.Receive} {
+		if d > 0 {
+			e.Time += d
+		}
+	}
+
+	return e
+}
+
+func harTimings(t *clientTrace) HARTimings {
+	ms := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() {
+			return -1
+		}
+		return float64(to.Sub(from)) / float64(time.Millisecond)
+	}
+
+	// the phase after the connection was obtained
+	connected := t.gotConn
+	if connected.IsZero() {
+		connected = t.connectDone
+	}
+
+	blockedEnd := connected
+	switch {
+	case !t.dnsStart.IsZero():
+		blockedEnd = t.dnsStart
+	case !t.connectStart.IsZero():
+		blockedEnd = t.connectStart
+	}
+
+	h := HARTimings{
+		Blocked: ms(t.getConn, blockedEnd),
+		DNS:     ms(t.dnsStart, t.dnsDone),
+		Connect: -1,
+		SSL:     ms(t.tlsStart, t.tlsDone),
+		Send:    ms(connected, t.wroteRequest),
+		Wait:    ms(t.wroteRequest, t.firstByte),
+		Receive: ms(t.firstByte, t.bodyDone),
+	}
+
+	// per the spec the connect time includes the SSL handshake
+	if !t.connectStart.IsZero() {
+		h.Connect = ms(t.connectStart, connected)
+	}
+
+	return h
+}
+
+func harHeaders(h http.Header) []HARNameValue {
+	nv := make([]HARNameValue, 0, len(h))
+	for k, vs := range h {
+		for _, v := range vs {
+			nv = append(nv, HARNameValue{Name: k, Value: v})
+		}
+	}
+	return nv
+}
+
+func harCookies(cookies []*http.Cookie) []HARCookie {
+	hc := make([]HARCookie, 0, len(cookies))
+	for _, c := range cookies {
+		cookie := HARCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			cookie.Expires = c.Expires.Format(time.RFC3339)
+		}
+		hc = append(hc, cookie)
+	}
+	return hc
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
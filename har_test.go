@@ -0,0 +1,84 @@
+package httpclient
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lets-go-go/httpclient/mock"
+)
+
+func TestHARRecorderRedirects(t *testing.T) {
+	type hop struct {
+		method   string
+		url      string
+		status   int
+		redirect string
+		postData string
+		content  string
+	}
+
+	tests := []struct {
+		status int
+		method string
+		body   string
+		want   []hop
+	}{
+		{http.StatusFound, "GET", "", []hop{
+			{"GET", "https://api.test/old", 302, "/new", "", "moved"},
+			{"GET", "https://api.test/new", 200, "", "", "done"},
+		}},
+		// 303 改为不带请求体的 GET
+		{http.StatusSeeOther, "POST", "a=1", []hop{
+			{"POST", "https://api.test/old", 303, "/new", "a=1", "moved"},
+			{"GET", "https://api.test/new", 200, "", "", "done"},
+		}},
+		// 307 原样重发请求体
+		{http.StatusTemporaryRedirect, "POST", "a=1", []hop{
+			{"POST", "https://api.test/old", 307, "/new", "a=1", "moved"},
+			{"POST", "https://api.test/new", 200, "", "a=1", "done"},
+		}},
+	}
+
+	for _, tt := range tests {
+		mt := mock.NewTransport()
+		mt.On(tt.method, "/old").ReplyString(tt.status, "moved").ReplyHeader("Location", "/new")
+		mt.On("", "/new").ReplyString(200, "done")
+
+		h := NewHARRecorder()
+		c := NewSession().SetTransport(mt).SetHARRecorder(h).To(tt.method, "https://api.test/old")
+		if tt.body != "" {
+			c.SendBody(tt.body)
+		}
+
+		res, err := c.Execute()
+		if err != nil {
+			t.Fatalf("%d: %v", tt.status, err)
+		}
+		if _, err := res.Raw(); err != nil {
+			t.Fatalf("%d: %v", tt.status, err)
+		}
+
+		entries := h.HAR().Log.Entries
+		if len(entries) != len(tt.want) {
+			t.Errorf("%d: %d entries recorded, want %d", tt.status, len(entries), len(tt.want))
+			continue
+		}
+
+		for i, e := range entries {
+			got := hop{
+				method:   e.Request.Method,
+				url:      e.Request.URL,
+				status:   e.Response.Status,
+				redirect: e.Response.RedirectURL,
+				content:  e.Response.Content.Text,
+			}
+			if e.Request.PostData != nil {
+				got.postData = e.Request.PostData.Text
+			}
+
+			if got != tt.want[i] {
+				t.Errorf("%d: entry %d = %+v, want %+v", tt.status, i, got, tt.want[i])
+			}
+		}
+	}
+}
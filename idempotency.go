@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key of a
+// request, which lets the server recognize the retries of a request it
+// already processed.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// NewIdempotencyKey returns a random UUID (version 4), the default
+// idempotency key.
+func NewIdempotencyKey() string {
+	var b [16]byte
+	rand.Read(b[:])
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// SetIdempotencyKeys attaches a key returned by gen to the requests whose
+// method is not idempotent, such as POST and PATCH, so they are retried
+// safely. nil disables it:
+//
+//	httpclient.Settings().SetIdempotencyKeys(httpclient.NewIdempotencyKey)
+func (c *ClientSetting) SetIdempotencyKeys(gen func() string) *ClientSetting {
+	c.idempotencyKeys = gen
+	return c
+}
+
+// SetIdempotencyKeys attaches a key returned by gen to the requests of the
+// session whose method is not idempotent, instead of the one set by
+// Settings().SetIdempotencyKeys.
+func (s *Session) SetIdempotencyKeys(gen func() string) *Session {
+	s.idempotencyKeys = gen
+	return s
+}
+
+// SetIdempotencyKey sets the idempotency key of the request, which is sent
+// by all its retries. An empty key is generated by the function set with
+// SetIdempotencyKeys, or by NewIdempotencyKey.
+func (c *Client) SetIdempotencyKey(key string) *Client {
+	if key == "" {
+		key = c.idempotencyKeys()()
+	}
+
+	return c.SetHeader(IdempotencyKeyHeader, key)
+}
+
+// idempotencyKeys returns the function generating the idempotency keys of
+// the request.
+func (c *Client) idempotencyKeys() func() string {
+	switch {
+	case c.session != nil && c.session.idempotencyKeys != nil:
+		return c.session.idempotencyKeys
+	case Settings().idempotencyKeys != nil:
+		return Settings().idempotencyKeys
+	}
+
+	return NewIdempotencyKey
+}
+
+// attachIdempotencyKey generates the idempotency key of a request whose
+// method is not idempotent when SetIdempotencyKeys was called. The key is
+// kept in the headers of the request, so it does not change when the
+// request is assembled again.
+func (c *Client) attachIdempotencyKey() {
+	if idempotent(c.method) || c.header.Get(IdempotencyKeyHeader) != "" {
+		return
+	}
+
+	if (c.session == nil || c.session.idempotencyKeys == nil) && Settings().idempotencyKeys == nil {
+		return
+	}
+
+	c.SetHeader(IdempotencyKeyHeader, c.idempotencyKeys()())
+}
+
+// idempotent reports whether sending a request with method several times
+// has the same effect as sending it once.
+func idempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,411 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// LogLevel is the verbosity of the request logs.
+type LogLevel int
+
+// Log levels.
+const (
+	LogOff     LogLevel = iota // 不记录
+	LogSummary                 // 方法、URL、状态码和耗时
+	LogHeaders                 // 加上请求和响应头
+	LogBodies                  // 加上请求和响应体
+)
+
+// Redacted replaces the values removed by the redaction policy.
+const Redacted = "REDACTED"
+
+// DefaultRedactedHeaders are always redacted from the logs.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// LogOptions configures what is logged.
+type LogOptions struct {
+	Level LogLevel
+
+	// MaxBodySize caps the logged bodies, it defaults to 4096 bytes.
+	MaxBodySize int
+
+	// RedactHeaders, RedactQuery and RedactJSONFields name the headers,
+	// query parameters and JSON object fields, at any depth, whose values
+	// are replaced with Redacted. DefaultRedactedHeaders are always
+	// redacted.
+	RedactHeaders    []string
+	RedactQuery      []string
+	RedactJSONFields []string
+}
+
+// LogRecord describes an event of a request. Its URL, headers and bodies
+// are already redacted.
+type LogRecord struct {
+	Message  string // "request", "retry", "response" or "request failed"
+	Method   string
+	URL      string
+	Status   int
+	Duration time.Duration
+	Attempt  int
+	Err      error
+
+	Header        http.Header // from LogHeaders
+	Body          []byte      // from LogBodies
+	BodyTruncated bool
+}
+
+// Logger receives the log records of the requests.
+type Logger interface {
+	Log(ctx context.Context, r *LogRecord)
+}
+
+// SlogLogger returns a Logger writing to l. Failed requests are logged at
+// the error level, retries at the warn level and the other records at the
+// info level.
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Log(ctx context.Context, r *LogRecord) {
+	level := slog.LevelInfo
+	switch {
+	case r.Message == "retry":
+		level = slog.LevelWarn
+	case r.Err != nil:
+		level = slog.LevelError
+	}
+
+	attrs := []slog.Attr{slog.String("method", r.Method), slog.String("url", r.URL)}
+
+	if r.Attempt > 1 {
+		attrs = append(attrs, slog.Int("attempt", r.Attempt))
+	}
+
+	if r.Status != 0 {
+		attrs = append(attrs, slog.Int("status", r.Status))
+	}
+
+	if r.Duration != 0 {
+		attrs = append(attrs, slog.Duration("duration", r.Duration))
+	}
+
+	if r.Err != nil {
+		attrs = append(attrs, slog.String("error", r.Err.Error()))
+	}
+
+	if r.Header != nil {
+		h := make([]any, 0, len(r.Header))
+		for k, vs := range r.Header {
+			h = append(h, slog.String(k, strings.Join(vs, ", ")))
+		}
+		attrs = append(attrs, slog.Group("header", h...))
+	}
+
+	if r.Body != nil {
+		body := string(r.Body)
+		if !utf8.Valid(r.Body) {
+			body = "[binary]"
+		}
+		attrs = append(attrs, slog.String("body", body))
+
+		if r.BodyTruncated {
+			attrs = append(attrs, slog.Bool("body_truncated", true))
+		}
+	}
+
+	s.l.LogAttrs(ctx, level, r.Message, attrs...)
+}
+
+type logging struct {
+	logger  Logger
+	opts    LogOptions
+	headers map[string]bool
+	query   map[string]bool
+	fields  map[string]bool
+}
+
+func newLogging(l Logger, opts LogOptions) *logging {
+	if l == nil || opts.Level == LogOff {
+		return nil
+	}
+
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 4096
+	}
+
+	g := &logging{
+		logger:  l,
+		opts:    opts,
+		headers: make(map[string]bool),
+		query:   make(map[string]bool),
+		fields:  make(map[string]bool),
+	}
+
+	for _, h := range append(DefaultRedactedHeaders, opts.RedactHeaders...) {
+		g.headers[http.CanonicalHeaderKey(h)] = true
+	}
+
+	for _, q := range opts.RedactQuery {
+		g.query[q] = true
+	}
+
+	for _, f := range opts.RedactJSONFields {
+		g.fields[f] = true
+	}
+
+	return g
+}
+
+// SetLogger sets the Logger of every request which is not sent from a
+// session with its own Logger.
+func (c *ClientSetting) SetLogger(l Logger, opts LogOptions) *ClientSetting {
+	c.logging = newLogging(l, opts)
+	return c
+}
+
+// logging returns the logging configuration of the request, nil when the
+// requests are not logged.
+func (c *Client) logging() *logging {
+	if c.session != nil && c.session.logging != nil {
+		return c.session.logging
+	}
+
+	return Settings().logging
+}
+
+func (g *logging) record(message string, req *http.Request) *LogRecord {
+	return &LogRecord{Message: message, Method: req.Method, URL: g.redactURL(req.URL)}
+}
+
+func (g *logging) request(req *http.Request) {
+	if g == nil {
+		return
+	}
+
+	r := g.record("request", req)
+
+	if g.opts.Level >= LogHeaders {
+		r.Header = g.redactHeader(req.Header)
+	}
+
+	if g.opts.Level >= LogBodies && req.GetBody != nil && req.Header.Get("Content-Encoding") == "" {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(body)
+			body.Close()
+
+			r.Body, r.BodyTruncated = g.redactBody(b, req.Header)
+		}
+	}
+
+	g.logger.Log(req.Context(), r)
+}
+
+func (g *logging) retry(req *http.Request, attempt int, err error) {
+	if g == nil {
+		return
+	}
+
+	r := g.record("retry", req)
+	r.Attempt, r.Err = attempt, g.redactError(err)
+
+	g.logger.Log(req.Context(), r)
+}
+
+func (g *logging) failure(req *http.Request, attempt int, d time.Duration, err error) {
+	if g == nil {
+		return
+	}
+
+	r := g.record("request failed", req)
+	r.Attempt, r.Duration, r.Err = attempt, d, g.redactError(err)
+
+	g.logger.Log(req.Context(), r)
+}
+
+// response logs res. At LogBodies the record is logged once the body is
+// read to the end or closed, with the beginning of the body read by then,
+// so that streamed bodies are not held back.
+func (g *logging) response(req *http.Request, res *http.Response, attempt int, d time.Duration) {
+	if g == nil {
+		return
+	}
+
+	r := g.record("response", req)
+	r.Status, r.Attempt, r.Duration = res.StatusCode, attempt, d
+
+	if g.opts.Level >= LogHeaders {
+		r.Header = g.redactHeader(res.Header)
+	}
+
+	if g.opts.Level >= LogBodies && res.Body != nil && res.Body != http.NoBody && res.Header.Get("Content-Encoding") == "" {
+		ctx := req.Context()
+		res.Body = &loggedBody{ReadCloser: res.Body, limit: g.opts.MaxBodySize + 1, log: func(b []byte, eof bool) {
+			r.Body, r.BodyTruncated = g.redactBody(b, res.Header)
+			r.BodyTruncated = r.BodyTruncated || !eof && res.ContentLength != int64(len(b))
+			g.logger.Log(ctx, r)
+		}}
+		return
+	}
+
+	g.logger.Log(req.Context(), r)
+}
+
+// loggedBody keeps the first limit bytes read from a response body and
+// passes them to log when the body is read to the end or closed, whichever
+// comes first. eof is false when the body was closed before its end was
+// read.
+type loggedBody struct {
+	io.ReadCloser
+	limit int
+	log   func(b []byte, eof bool)
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	logged bool
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.mu.Lock()
+	if room := b.limit - b.buf.Len(); room > 0 && !b.logged {
+		b.buf.Write(p[:min(n, room)])
+	}
+	b.mu.Unlock()
+
+	if err != nil {
+		b.flush(err == io.EOF)
+	}
+
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	b.flush(false)
+	return b.ReadCloser.Close()
+}
+
+func (b *loggedBody) flush(eof bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.logged {
+		return
+	}
+
+	b.logged = true
+	b.log(b.buf.Bytes(), eof)
+}
+
+func (g *logging) redactURL(u *url.URL) string {
+	if len(g.query) == 0 || u.RawQuery == "" {
+		return u.String()
+	}
+
+	q := u.Query()
+	for k, vs := range q {
+		if g.query[k] {
+			for i := range vs {
+				vs[i] = Redacted
+			}
+		}
+	}
+
+	r := *u
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+// redactError redacts the URL of the errors returned by http.Client.
+func (g *logging) redactError(err error) error {
+	if e, ok := err.(*url.Error); ok && len(g.query) > 0 {
+		if u, perr := url.Parse(e.URL); perr == nil {
+			return &url.Error{Op: e.Op, URL: g.redactURL(u), Err: e.Err}
+		}
+	}
+	return err
+}
+
+func (g *logging) redactHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+
+	for k, vs := range h {
+		if g.headers[http.CanonicalHeaderKey(k)] {
+			c[k] = []string{Redacted}
+			continue
+		}
+		c[k] = append([]string(nil), vs...)
+	}
+
+	return c
+}
+
+// redactBody truncates b to the size cap and redacts its JSON fields. A
+// JSON body which cannot be parsed, because it is truncated or invalid, is
+// omitted when JSON fields are redacted.
+func (g *logging) redactBody(b []byte, h http.Header) ([]byte, bool) {
+	truncated := len(b) > g.opts.MaxBodySize
+	if truncated {
+		b = b[:g.opts.MaxBodySize]
+	}
+
+	if len(g.fields) == 0 || !strings.Contains(h.Get("Content-Type"), "json") {
+		return b, truncated
+	}
+
+	var v interface{}
+	if truncated || json.Unmarshal(b, &v) != nil {
+		return []byte{}, truncated
+	}
+
+	if !RedactJSONValue(v, g.fields) {
+		return b, false
+	}
+
+	if r, err := json.Marshal(v); err == nil {
+		return r, false
+	}
+
+	return []byte{}, false
+}
+
+// RedactJSONValue replaces with Redacted the values of the object fields
+// named in fields, at any depth, of v which is a JSON value decoded into an
+// interface{}. v is redacted in place, it reports whether anything changed.
+func RedactJSONValue(v interface{}, fields map[string]bool) bool {
+	changed := false
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if fields[k] {
+				v[k] = Redacted
+				changed = true
+			} else if RedactJSONValue(e, fields) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if RedactJSONValue(e, fields) {
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
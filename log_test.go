@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordLogger struct {
+	mu      sync.Mutex
+	records []*LogRecord
+}
+
+func (l *recordLogger) Log(ctx context.Context, r *LogRecord) {
+	l.mu.Lock()
+	l.records = append(l.records, r)
+	l.mu.Unlock()
+}
+
+func (l *recordLogger) last() *LogRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.records) == 0 {
+		return nil
+	}
+	return l.records[len(l.records)-1]
+}
+
+// The response is logged once its body is read, without holding back a
+// streamed body until MaxBodySize bytes arrive.
+func TestLogStreamedResponseBody(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: 2\n\n"))
+	}))
+	defer srv.Close()
+
+	l := new(recordLogger)
+	s := NewSession().SetLogger(l, LogOptions{Level: LogBodies})
+
+	done := make(chan *Response)
+	go func() {
+		res, err := s.To("GET", srv.URL).Execute()
+		if err != nil {
+			t.Error(err)
+		}
+		done <- res
+	}()
+
+	var res *Response
+	select {
+	case res = <-done:
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("Execute waited for the streamed body")
+	}
+
+	if r := l.last(); r == nil || r.Message != "request" {
+		t.Errorf("last record = %+v before the body is read, want the request", r)
+	}
+
+	close(release)
+	b, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := l.last()
+	if r == nil || r.Message != "response" {
+		t.Fatalf("last record = %+v, want the response", r)
+	}
+	if string(r.Body) != string(b) || r.BodyTruncated {
+		t.Errorf("logged body = %q, truncated %v, want %q", r.Body, r.BodyTruncated, b)
+	}
+}
+
+func TestLogResponseBodyTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		read      bool
+		body      string
+		truncated bool
+	}{
+		{true, "01234", true},
+		{false, "", true},
+	}
+
+	for _, tt := range tests {
+		l := new(recordLogger)
+		res, err := NewSession().SetLogger(l, LogOptions{Level: LogBodies, MaxBodySize: 5}).To("GET", srv.URL).Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.read {
+			ioutil.ReadAll(res.Body)
+		}
+		res.Body.Close()
+
+		r := l.last()
+		if r == nil || r.Message != "response" {
+			t.Fatalf("read %v: last record = %+v, want the response", tt.read, r)
+		}
+		if string(r.Body) != tt.body || r.BodyTruncated != tt.truncated {
+			t.Errorf("read %v: logged body = %q, truncated %v, want %q, %v", tt.read, r.Body, r.BodyTruncated, tt.body, tt.truncated)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Labels identify the requests a measurement belongs to.
+type Labels struct {
+	Method string
+	Host   string
+	Status string // "2xx" 到 "5xx"，没有收到响应时为 "error"，进行中的请求为空
+}
+
+// Metrics receives the measurements of the requests sent by the package.
+// Implementations must be safe for concurrent use. PrometheusMetrics and
+// ExpvarMetrics are provided.
+type Metrics interface {
+	// RequestStarted is called before every attempt of a request.
+	RequestStarted(l Labels)
+
+	// RequestDone is called when the response headers of an attempt were
+	// received or the attempt failed, d is the latency of the attempt.
+	RequestDone(l Labels, d time.Duration, bytesSent int64)
+
+	// ResponseRead is called when the response body was read to the end
+	// or closed.
+	ResponseRead(l Labels, bytesReceived int64)
+
+	// Retried is called before a failed request is retried.
+	Retried(l Labels)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) RequestStarted(Labels)                    {}
+func (nopMetrics) RequestDone(Labels, time.Duration, int64) {}
+func (nopMetrics) ResponseRead(Labels, int64)               {}
+func (nopMetrics) Retried(Labels)                           {}
+
+// SetMetrics sets the Metrics receiving the measurements of all requests
+// which are not sent from a session with its own Metrics.
+func (c *ClientSetting) SetMetrics(m Metrics) *ClientSetting {
+	c.metrics = m
+	return c
+}
+
+func statusClass(res *http.Response, err error) string {
+	if err != nil || res == nil {
+		return "error"
+	}
+	return strconv.Itoa(res.StatusCode/100) + "xx"
+}
+
+// metrics returns the Metrics of the request.
+func (c *Client) metrics() Metrics {
+	if c.session != nil && c.session.metrics != nil {
+		return c.session.metrics
+	}
+
+	if m := Settings().metrics; m != nil {
+		return m
+	}
+
+	return nopMetrics{}
+}
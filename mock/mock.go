@@ -0,0 +1,479 @@
+// Package mock provides an http.RoundTripper answering requests with canned
+// responses, so tests using httpclient never touch the network:
+//
+//	mt := mock.NewTransport().Strict()
+//	mt.On("GET", "/users/*").ReplyJSON(200, User{Name: "bob"})
+//	mt.On("POST", "/users").JSONBody(map[string]string{"name": "al"}).Reply(201).Once()
+//
+//	session := httpclient.NewSession().SetBaseURL("http://api.test").SetTransport(mt)
+//	...
+//	mt.AssertExpectations(t)
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// TB is the subset of testing.TB used by the assertions.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Call records a request handled by the Transport. Request is a copy of
+// the request sent whose Body reads Body.
+type Call struct {
+	Request *http.Request
+	Body    []byte
+	Mock    *Mock // nil for unmatched requests
+}
+
+// Transport is an http.RoundTripper which answers requests from the mocks
+// registered with On. Mocks are tried in registration order, a mock whose
+// Times are used up no longer matches.
+type Transport struct {
+	mu     sync.Mutex
+	mocks  []*Mock
+	calls  []*Call
+	strict bool
+}
+
+// NewTransport returns a new instance of Transport.
+func NewTransport() *Transport {
+	return &Transport{}
+}
+
+// Strict makes requests which match no mock fail with an error, and makes
+// AssertExpectations report them. Otherwise they get a 404 response.
+func (t *Transport) Strict() *Transport {
+	t.strict = true
+	return t
+}
+
+// On registers a mock for requests with the given method and URL pattern.
+// An empty method matches any method. The pattern may contain "*" wildcards
+// matching any sequence of characters; it is matched against the path of
+// the request when it starts with "/", or against the full URL otherwise.
+// The query is only compared when the pattern contains "?".
+func (t *Transport) On(method, pattern string) *Mock {
+	return t.add(&Mock{method: method, url: globRegexp(pattern), pattern: pattern})
+}
+
+// OnRegexp is like On but matches the full URL of the request against re.
+func (t *Transport) OnRegexp(method string, re *regexp.Regexp) *Mock {
+	return t.add(&Mock{method: method, url: re, pattern: re.String(), full: true})
+}
+
+func (t *Transport) add(m *Mock) *Mock {
+	m.t = t
+	m.status = http.StatusOK
+	m.header = make(http.Header)
+	m.min = 1
+	m.max = -1
+
+	if !m.full {
+		m.full = !strings.HasPrefix(m.pattern, "/")
+	}
+
+	t.mu.Lock()
+	t.mocks = append(t.mocks, m)
+	t.mu.Unlock()
+
+	return m
+}
+
+// RoundTrip implements http.RoundTripper. The request is not modified, the
+// matchers and ReplyFunc get a copy of it whose body can be read.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		body = b
+	}
+
+	req = copyRequest(req, body)
+
+	t.mu.Lock()
+
+	call := &Call{Request: req, Body: body}
+	t.calls = append(t.calls, call)
+
+	for _, m := range t.mocks {
+		if m.matches(req, body) {
+			m.calls++
+			call.Mock = m
+			break
+		}
+	}
+
+	t.mu.Unlock()
+
+	if call.Mock == nil {
+		if t.strict {
+			return nil, fmt.Errorf("mock: no mock matches %s %s", req.Method, req.URL)
+		}
+		return newResponse(req, http.StatusNotFound, nil, nil), nil
+	}
+
+	return call.Mock.respond(req)
+}
+
+// Calls returns all requests handled so far.
+func (t *Transport) Calls() []*Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]*Call(nil), t.calls...)
+}
+
+// Unmatched returns the requests which matched no mock.
+func (t *Transport) Unmatched() []*Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var calls []*Call
+	for _, c := range t.calls {
+		if c.Mock == nil {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// AssertExpectations reports every mock called fewer or more times than
+// expected and, in strict mode, every unmatched request.
+func (t *Transport) AssertExpectations(tb TB) bool {
+	tb.Helper()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ok := true
+
+	for _, m := range t.mocks {
+		if m.calls < m.min || (m.max >= 0 && m.calls > m.max) {
+			tb.Errorf("mock: %s was called %d times, expected %s", m, m.calls, m.expected())
+			ok = false
+		}
+	}
+
+	if t.strict {
+		for _, c := range t.calls {
+			if c.Mock == nil {
+				tb.Errorf("mock: unexpected request %s %s", c.Request.Method, c.Request.URL)
+				ok = false
+			}
+		}
+	}
+
+	return ok
+}
+
+// AssertCalled reports whether a request with the method and URL pattern
+// was handled, see On for the pattern syntax.
+func (t *Transport) AssertCalled(tb TB, method, pattern string) bool {
+	tb.Helper()
+
+	m := &Mock{method: method, url: globRegexp(pattern), pattern: pattern, full: !strings.HasPrefix(pattern, "/"), max: -1}
+
+	for _, c := range t.Calls() {
+		if m.matches(c.Request, c.Body) {
+			return true
+		}
+	}
+
+	tb.Errorf("mock: expected a call to %s", m)
+	return false
+}
+
+// Reset removes all mocks and recorded calls.
+func (t *Transport) Reset() {
+	t.mu.Lock()
+	t.mocks, t.calls = nil, nil
+	t.mu.Unlock()
+}
+
+// Mock is a request expectation and its canned response.
+type Mock struct {
+	t        *Transport
+	method   string
+	pattern  string
+	url      *regexp.Regexp
+	full     bool
+	headers  http.Header
+	body     interface{}
+	hasBody  bool
+	matchers []func(*http.Request) bool
+
+	status    int
+	header    http.Header
+	respBody  []byte
+	err       error
+	responder func(*http.Request) (*http.Response, error)
+
+	min, max int
+	calls    int
+}
+
+// Header requires the request header key to have the value.
+func (m *Mock) Header(key, value string) *Mock {
+	if m.headers == nil {
+		m.headers = make(http.Header)
+	}
+	m.headers.Add(key, value)
+	return m
+}
+
+// JSONBody requires the request body to be JSON equal to v, which is
+// compared after marshaling, so v may be a struct, a map or a JSON string.
+func (m *Mock) JSONBody(v interface{}) *Mock {
+	m.body, m.hasBody = normalizeJSON(v), true
+	return m
+}
+
+// Match adds a custom matcher.
+func (m *Mock) Match(fn func(*http.Request) bool) *Mock {
+	m.matchers = append(m.matchers, fn)
+	return m
+}
+
+// Reply sets the status code of the response.
+func (m *Mock) Reply(status int) *Mock {
+	m.status = status
+	return m
+}
+
+// ReplyString sets the status code and the text body of the response.
+func (m *Mock) ReplyString(status int, body string) *Mock {
+	m.status, m.respBody = status, []byte(body)
+	return m
+}
+
+// ReplyJSON sets the status code of the response and its body to v encoded
+// as JSON.
+func (m *Mock) ReplyJSON(status int, v interface{}) *Mock {
+	b, err := json.Marshal(v)
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	m.status, m.respBody = status, b
+	return m.ReplyHeader("Content-Type", "application/json")
+}
+
+// ReplyHeader sets a header of the response.
+func (m *Mock) ReplyHeader(key, value string) *Mock {
+	m.header.Set(key, value)
+	return m
+}
+
+// ReplyError makes the round trip fail with err.
+func (m *Mock) ReplyError(err error) *Mock {
+	m.err = err
+	return m
+}
+
+// ReplyFunc answers the request with fn.
+func (m *Mock) ReplyFunc(fn func(*http.Request) (*http.Response, error)) *Mock {
+	m.responder = fn
+	return m
+}
+
+// Times sets the number of times the mock is expected to be called, it no
+// longer matches once used up.
+func (m *Mock) Times(n int) *Mock {
+	m.min, m.max = n, n
+	return m
+}
+
+// Once is Times(1).
+func (m *Mock) Once() *Mock {
+	return m.Times(1)
+}
+
+// AnyTimes makes the mock optional and reusable.
+func (m *Mock) AnyTimes() *Mock {
+	m.min, m.max = 0, -1
+	return m
+}
+
+// Calls returns how many times the mock was called.
+func (m *Mock) Calls() int {
+	m.t.mu.Lock()
+	defer m.t.mu.Unlock()
+
+	return m.calls
+}
+
+func (m *Mock) String() string {
+	method := m.method
+	if method == "" {
+		method = "*"
+	}
+	return method + " " + m.pattern
+}
+
+func (m *Mock) expected() string {
+	switch {
+	case m.max < 0:
+		return fmt.Sprintf("at least %d", m.min)
+	case m.min == m.max:
+		return fmt.Sprint(m.min)
+	}
+	return fmt.Sprintf("%d to %d", m.min, m.max)
+}
+
+func (m *Mock) matches(req *http.Request, body []byte) bool {
+	if m.max >= 0 && m.calls >= m.max {
+		return false
+	}
+
+	if m.method != "" && !strings.EqualFold(m.method, req.Method) {
+		return false
+	}
+
+	if !m.url.MatchString(m.target(req)) {
+		return false
+	}
+
+	for k, vs := range m.headers {
+		for _, v := range vs {
+			if !contains(req.Header[http.CanonicalHeaderKey(k)], v) {
+				return false
+			}
+		}
+	}
+
+	if m.hasBody {
+		var v interface{}
+		if json.Unmarshal(body, &v) != nil || !reflect.DeepEqual(v, m.body) {
+			return false
+		}
+	}
+
+	for _, fn := range m.matchers {
+		if !fn(req) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *Mock) target(req *http.Request) string {
+	u := *req.URL
+
+	if !strings.Contains(m.pattern, "?") {
+		u.RawQuery = ""
+	}
+
+	if m.full {
+		return u.String()
+	}
+
+	if u.RawQuery != "" {
+		return u.EscapedPath() + "?" + u.RawQuery
+	}
+	return u.EscapedPath()
+}
+
+func (m *Mock) respond(req *http.Request) (*http.Response, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if m.responder != nil {
+		return m.responder(req)
+	}
+
+	return newResponse(req, m.status, m.header, m.respBody), nil
+}
+
+// copyRequest returns a copy of req whose body reads body.
+func copyRequest(req *http.Request, body []byte) *http.Request {
+	r := req.Clone(req.Context())
+
+	if req.Body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	return r
+}
+
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	h := make(http.Header)
+	for k, vs := range header {
+		h[k] = append([]string(nil), vs...)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func globRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+func normalizeJSON(v interface{}) interface{} {
+	var b []byte
+
+	switch v := v.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return err
+	}
+	return out
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
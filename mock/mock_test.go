@@ -0,0 +1,230 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeTB records the errors of the assertions.
+type fakeTB struct {
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func newRequest(method, url, body string, header ...string) *http.Request {
+	var req *http.Request
+	if body == "" {
+		req, _ = http.NewRequest(method, url, nil)
+	} else {
+		req, _ = http.NewRequest(method, url, strings.NewReader(body))
+	}
+
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Add(header[i], header[i+1])
+	}
+	return req
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		mock  func(t *Transport) *Mock
+		req   *http.Request
+		match bool
+	}{
+		{"path", func(t *Transport) *Mock { return t.On("GET", "/users") }, newRequest("GET", "http://api.test/users", ""), true},
+		{"path ignores query", func(t *Transport) *Mock { return t.On("GET", "/users") }, newRequest("GET", "http://api.test/users?page=2", ""), true},
+		{"path with query", func(t *Transport) *Mock { return t.On("GET", "/users?page=2") }, newRequest("GET", "http://api.test/users?page=3", ""), false},
+		{"glob", func(t *Transport) *Mock { return t.On("GET", "/users/*/repos") }, newRequest("GET", "http://api.test/users/bob/repos", ""), true},
+		{"glob no match", func(t *Transport) *Mock { return t.On("GET", "/users/*") }, newRequest("GET", "http://api.test/teams/1", ""), false},
+		{"full URL", func(t *Transport) *Mock { return t.On("GET", "https://api.test/*") }, newRequest("GET", "https://api.test/a", ""), true},
+		{"full URL other host", func(t *Transport) *Mock { return t.On("GET", "https://api.test/*") }, newRequest("GET", "https://other.test/a", ""), false},
+		{"any method", func(t *Transport) *Mock { return t.On("", "/a") }, newRequest("PATCH", "http://api.test/a", ""), true},
+		{"method case", func(t *Transport) *Mock { return t.On("get", "/a") }, newRequest("GET", "http://api.test/a", ""), true},
+		{"other method", func(t *Transport) *Mock { return t.On("POST", "/a") }, newRequest("GET", "http://api.test/a", ""), false},
+		{"header", func(t *Transport) *Mock { return t.On("GET", "/a").Header("x-token", "1") }, newRequest("GET", "http://api.test/a", "", "X-Token", "1"), true},
+		{"header mismatch", func(t *Transport) *Mock { return t.On("GET", "/a").Header("X-Token", "1") }, newRequest("GET", "http://api.test/a", "", "X-Token", "2"), false},
+		{"JSON body", func(t *Transport) *Mock { return t.On("POST", "/a").JSONBody(map[string]int{"a": 1, "b": 2}) }, newRequest("POST", "http://api.test/a", `{"b": 2, "a": 1}`), true},
+		{"JSON string body", func(t *Transport) *Mock { return t.On("POST", "/a").JSONBody(`[1, 2]`) }, newRequest("POST", "http://api.test/a", `[1,2]`), true},
+		{"JSON body mismatch", func(t *Transport) *Mock { return t.On("POST", "/a").JSONBody(map[string]int{"a": 1}) }, newRequest("POST", "http://api.test/a", `{"a": 2}`), false},
+		{"invalid JSON body", func(t *Transport) *Mock { return t.On("POST", "/a").JSONBody(map[string]int{"a": 1}) }, newRequest("POST", "http://api.test/a", `{"a":`), false},
+		{"matcher", func(t *Transport) *Mock {
+			return t.On("POST", "/a").Match(func(r *http.Request) bool {
+				b, _ := ioutil.ReadAll(r.Body)
+				return string(b) == "hello"
+			})
+		}, newRequest("POST", "http://api.test/a", "hello"), true},
+		{"matcher false", func(t *Transport) *Mock {
+			return t.On("GET", "/a").Match(func(r *http.Request) bool { return false })
+		}, newRequest("GET", "http://api.test/a", ""), false},
+	}
+
+	for _, tt := range tests {
+		mt := NewTransport()
+		m := tt.mock(mt).Reply(204)
+
+		res, err := mt.RoundTrip(tt.req)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		want := 404
+		if tt.match {
+			want = 204
+		}
+		if res.StatusCode != want {
+			t.Errorf("%s: status %d, want %d", tt.name, res.StatusCode, want)
+		}
+
+		if got := len(mt.Unmatched()) == 0; got != tt.match || (m.Calls() == 1) != tt.match {
+			t.Errorf("%s: matched %v, mock calls %d, want matched %v", tt.name, got, m.Calls(), tt.match)
+		}
+	}
+}
+
+func TestMockOrderAndTimes(t *testing.T) {
+	mt := NewTransport()
+	first := mt.On("GET", "/a").ReplyString(200, "first").Once()
+	second := mt.On("GET", "/*").ReplyString(200, "second")
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		res, err := mt.RoundTrip(newRequest("GET", "http://api.test/a", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(res.Body)
+		got = append(got, string(b))
+	}
+
+	if strings.Join(got, ",") != "first,second,second" {
+		t.Errorf("bodies = %q, want first, second, second", got)
+	}
+	if first.Calls() != 1 || second.Calls() != 2 {
+		t.Errorf("calls = %d, %d, want 1, 2", first.Calls(), second.Calls())
+	}
+}
+
+func TestUnmatched(t *testing.T) {
+	tests := []struct {
+		strict bool
+		errors int
+	}{
+		{false, 0},
+		{true, 1},
+	}
+
+	for _, tt := range tests {
+		mt := NewTransport()
+		if tt.strict {
+			mt.Strict()
+		}
+		mt.On("GET", "/a").AnyTimes()
+
+		res, err := mt.RoundTrip(newRequest("DELETE", "http://api.test/b", ""))
+		switch {
+		case tt.strict && err == nil:
+			t.Errorf("strict: unmatched request succeeded with %d", res.StatusCode)
+		case !tt.strict && (err != nil || res.StatusCode != 404):
+			t.Errorf("lenient: unmatched request = %v, %v, want a 404 response", res, err)
+		}
+
+		calls := mt.Unmatched()
+		if len(calls) != 1 || calls[0].Request.Method != "DELETE" || calls[0].Mock != nil {
+			t.Errorf("strict %v: Unmatched() = %v, want the DELETE request", tt.strict, calls)
+		}
+
+		tb := new(fakeTB)
+		if ok := mt.AssertExpectations(tb); ok != (tt.errors == 0) || len(tb.errors) != tt.errors {
+			t.Errorf("strict %v: AssertExpectations = %v, %q, want %d errors", tt.strict, ok, tb.errors, tt.errors)
+		}
+	}
+}
+
+func TestAssertExpectations(t *testing.T) {
+	mt := NewTransport()
+	mt.On("GET", "/once").Once()
+	mt.On("GET", "/twice").Times(2)
+	mt.On("GET", "/optional").AnyTimes()
+
+	mt.RoundTrip(newRequest("GET", "http://api.test/once", ""))
+	mt.RoundTrip(newRequest("GET", "http://api.test/twice", ""))
+
+	tb := new(fakeTB)
+	if mt.AssertExpectations(tb) || len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "/twice was called 1 times, expected 2") {
+		t.Errorf("AssertExpectations errors = %q, want /twice called too few times", tb.errors)
+	}
+
+	tb = new(fakeTB)
+	if !mt.AssertCalled(tb, "GET", "/once") || mt.AssertCalled(tb, "POST", "/once") || len(tb.errors) != 1 {
+		t.Errorf("AssertCalled errors = %q, want one for POST /once", tb.errors)
+	}
+}
+
+func TestReplies(t *testing.T) {
+	boom := errors.New("boom")
+
+	mt := NewTransport()
+	mt.On("GET", "/json").ReplyJSON(201, map[string]int{"a": 1})
+	mt.On("GET", "/error").ReplyError(boom)
+	mt.On("POST", "/func").ReplyFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(r.Body)
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(strings.ToUpper(string(b))))}, nil
+	})
+
+	res, err := mt.RoundTrip(newRequest("GET", "http://api.test/json", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 201 || res.Header.Get("Content-Type") != "application/json" || string(b) != `{"a":1}` {
+		t.Errorf("ReplyJSON = %d %v %q", res.StatusCode, res.Header, b)
+	}
+
+	if _, err := mt.RoundTrip(newRequest("GET", "http://api.test/error", "")); err != boom {
+		t.Errorf("ReplyError = %v, want %v", err, boom)
+	}
+
+	res, err = mt.RoundTrip(newRequest("POST", "http://api.test/func", "abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(res.Body); string(b) != "ABC" {
+		t.Errorf("ReplyFunc body = %q, want ABC", b)
+	}
+}
+
+// RoundTrip must not modify the request of the caller.
+func TestRoundTripKeepsRequest(t *testing.T) {
+	mt := NewTransport()
+	mt.On("POST", "/a").JSONBody(map[string]int{"a": 1}).Reply(204)
+
+	req := newRequest("POST", "http://api.test/a", `{"a":1}`)
+	body := req.Body
+
+	if _, err := mt.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Body != body {
+		t.Error("RoundTrip replaced the body of the request")
+	}
+
+	call := mt.Calls()[0]
+	if call.Request == req || string(call.Body) != `{"a":1}` {
+		t.Errorf("call = %p %q, want a copy of the request with its body", call.Request, call.Body)
+	}
+	if b, _ := ioutil.ReadAll(call.Request.Body); string(b) != `{"a":1}` {
+		t.Errorf("recorded request body = %q", b)
+	}
+}
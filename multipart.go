@@ -0,0 +1,166 @@
+package httpclient
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// attachment is a file added to the multipart form. It is read when the
+// request is sent and kept to export the request as a curl command.
+type attachment struct {
+	field    string
+	path     string
+	filename string
+	ranged   bool
+	start    int64
+	length   int64
+}
+
+// size returns the number of bytes of the file sent.
+func (a attachment) size() (int64, error) {
+	if a.ranged {
+		return a.length, nil
+	}
+
+	fi, err := os.Stat(a.path)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// copyTo writes the file, or its range, to w.
+func (a attachment) copyTo(w io.Writer) error {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if !a.ranged {
+		_, err = io.Copy(w, f)
+		return err
+	}
+
+	if _, err := f.Seek(a.start, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, f, a.length)
+	return err
+}
+
+// writeMultipart writes the attachments then the form fields to mw, file
+// writes the content of the attachments.
+func (c *Client) writeMultipart(mw *multipart.Writer, file func(w io.Writer, a attachment) error) error {
+	for _, a := range c.files {
+		w, err := mw.CreateFormFile(a.field, a.filename)
+		if err != nil {
+			return err
+		}
+
+		if err := file(w, a); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range sortedKeys(c.formVals) {
+		for _, v := range c.formVals[k] {
+			if err := mw.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return mw.Close()
+}
+
+// multipartBody returns the function opening the multipart form of the
+// request with the given boundary, and its size. The files are streamed
+// while the body is sent.
+func (c *Client) multipartBody(boundary string) (func() (io.ReadCloser, error), int64, error) {
+	var (
+		n    countWriter
+		size int64
+	)
+
+	// 先用计数的 writer 计算长度，文件只统计大小
+	mw := multipart.NewWriter(&n)
+	mw.SetBoundary(boundary)
+	err := c.writeMultipart(mw, func(w io.Writer, a attachment) error {
+		s, err := a.size()
+		size += s
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	getBody := func() (io.ReadCloser, error) {
+		return &multipartBody{write: func(w io.Writer) error {
+			mw := multipart.NewWriter(w)
+			mw.SetBoundary(boundary)
+			return c.writeMultipart(mw, func(w io.Writer, a attachment) error {
+				return a.copyTo(w)
+			})
+		}}, nil
+	}
+
+	return getBody, size + int64(n), nil
+}
+
+// multipartBody writes the form through a pipe from the first Read, so a
+// request which is assembled but not sent does not open its files.
+type multipartBody struct {
+	write func(w io.Writer) error
+	once  sync.Once
+	pr    *io.PipeReader
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.once.Do(func() {
+		pr, pw := io.Pipe()
+		b.pr = pr
+
+		go func() {
+			pw.CloseWithError(b.write(pw))
+		}()
+	})
+
+	if b.pr == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return b.pr.Read(p)
+}
+
+func (b *multipartBody) Close() error {
+	b.once.Do(func() {})
+
+	if b.pr != nil {
+		return b.pr.Close()
+	}
+	return nil
+}
+
+type countWriter int64
+
+func (n *countWriter) Write(p []byte) (int, error) {
+	*n += countWriter(len(p))
+	return len(p), nil
+}
+
+// assembleMultipart sets the multipart form as the body of the assembled
+// request.
+func (c *Client) assembleMultipart(req *http.Request, boundary string) error {
+	getBody, size, err := c.multipartBody(boundary)
+	if err != nil {
+		return err
+	}
+
+	req.Body, _ = getBody()
+	req.GetBody = getBody
+	req.ContentLength = size
+	return nil
+}
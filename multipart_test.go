@@ -0,0 +1,111 @@
+package httpclient
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMultipartForm(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(file, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	type part struct{ name, filename, content string }
+	var (
+		got    []part
+		length int64
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, length = nil, r.ContentLength
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			r.Body = zr
+		}
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			b, _ := ioutil.ReadAll(p)
+			got = append(got, part{p.FormName(), p.FileName(), string(b)})
+		}
+	}))
+	defer srv.Close()
+
+	want := []part{
+		{"file", "data.txt", "0123456789"},
+		{"range", "r.txt", "2345"},
+		{"a", "", "1"},
+		{"b", "", "2"},
+	}
+
+	tests := []struct {
+		name string
+		comp Compression
+	}{
+		{"plain", Compression{}},
+		{"gzip", Compression{Encoding: "gzip", MinSize: -1}},
+	}
+
+	for _, tt := range tests {
+		res, err := New().To("POST", srv.URL).
+			SetCompression(tt.comp).
+			AttachFile("file", file, "").
+			AttachFileRange("range", file, "r.txt", 2, 4).
+			AddField("b", "2").
+			AddField("a", "1").
+			Execute()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		res.Body.Close()
+
+		if len(got) != len(want) {
+			t.Fatalf("%s: parts = %q, want %q", tt.name, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: part %d = %q, want %q", tt.name, i, got[i], want[i])
+			}
+		}
+
+		if tt.comp.Encoding == "" && length <= 0 {
+			t.Errorf("%s: Content-Length = %d, want the size of the form", tt.name, length)
+		}
+	}
+}
+
+func TestAttachFileMissing(t *testing.T) {
+	_, err := New().To("POST", "http://localhost").
+		AttachFile("file", filepath.Join(t.TempDir(), "missing"), "").
+		Execute()
+	if !os.IsNotExist(err) {
+		t.Errorf("Execute() = %v, want a not exist error", err)
+	}
+}
@@ -0,0 +1,303 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PoolOptions configures the connection pool of a transport.
+type PoolOptions struct {
+	MaxIdleConns        int           // idle connections kept for all hosts, 0 means no limit
+	MaxIdleConnsPerHost int           // idle connections kept per host, 0 means http.DefaultMaxIdleConnsPerHost
+	MaxConnsPerHost     int           // connections per host, dialing or in use included, 0 means no limit
+	IdleConnTimeout     time.Duration // how long an idle connection is kept, 0 means no limit
+	KeepAlive           time.Duration // TCP keep-alive period, 0 means 15s and a negative value disables it
+	DisableKeepAlives   bool          // one request per connection
+}
+
+// DefaultPoolOptions are the pool options of the transports created by the
+// package until SetPool is called.
+var DefaultPoolOptions = PoolOptions{
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: http.DefaultMaxIdleConnsPerHost,
+	IdleConnTimeout:     90 * time.Second,
+	KeepAlive:           30 * time.Second,
+}
+
+func (o PoolOptions) apply(t *http.Transport) {
+	t.MaxIdleConns = o.MaxIdleConns
+	t.MaxIdleConnsPerHost = o.MaxIdleConnsPerHost
+	t.MaxConnsPerHost = o.MaxConnsPerHost
+	t.IdleConnTimeout = o.IdleConnTimeout
+	t.DisableKeepAlives = o.DisableKeepAlives
+}
+
+// dialer returns the net.Dialer of the transports created by the package.
+func (o PoolOptions) dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: o.KeepAlive,
+	}
+}
+
+// SetPool sets the pool options of the transport of the requests. The TCP
+// keep-alive period applies to the connections dialed without a Dialer set
+// by SetDialer.
+func (c *ClientSetting) SetPool(o PoolOptions) *ClientSetting {
+	c.pool = o
+	c.reinstall()
+	return c
+}
+
+// install sets t, configured with the protocol, the pool options and the
+// dialer of the settings, as ProxyTransport. The idle connections of the
+// transport it replaces are closed. An unconfigured copy of t is kept for
+// reinstall.
+func (c *ClientSetting) install(t *http.Transport) {
+	c.base = t.Clone()
+
+	c.protocol.apply(t)
+	c.pool.apply(t)
+
+	if t.Dial == nil {
+		t.DialContext = c.dialContext(c.pool.dialer())
+	}
+
+	// 旧的 transport 不再使用，释放它的空闲连接和统计
+	if old := c.ProxyTransport; old != nil && old != t {
+		old.CloseIdleConnections()
+		trackers.Delete(old)
+	}
+
+	trackTransport(t)
+	c.ProxyTransport = t
+}
+
+// reinstall installs a new copy of the transport of SetProxy configured
+// with the current settings. The transport in use is left untouched since
+// requests may be running on it.
+func (c *ClientSetting) reinstall() {
+	base := c.base
+	switch {
+	case c.ProxyTransport == nil:
+		base = http.DefaultTransport.(*http.Transport)
+	case trackerOf(c.ProxyTransport) == nil || base == nil:
+		// ProxyTransport 被直接赋值
+		base = c.ProxyTransport
+	}
+
+	c.install(base.Clone())
+}
+
+// PoolStats returns the statistics of the connections of the transport of
+// the requests, keyed by the "host:port" address dialed, which is the one
+// of the proxy when a proxy is used.
+func (c *ClientSetting) PoolStats() map[string]PoolStats {
+	return trackerOf(c.ProxyTransport).stats()
+}
+
+// CloseIdleConnections closes the idle connections of the transport of the
+// requests, the connections in use are left open.
+func (c *ClientSetting) CloseIdleConnections() {
+	if c.ProxyTransport != nil {
+		c.ProxyTransport.CloseIdleConnections()
+		return
+	}
+
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+}
+
+// SetPool sets the pool options of the requests of the session, which then
+// get a connection pool of their own instead of sharing the one set by
+// Settings().SetPool.
+func (s *Session) SetPool(o PoolOptions) *Session {
+	s.transports.update(func(t *sessionTransport) { t.pool = &o })
+	return s
+}
+
+// PoolStats returns the statistics of the connections of the transport of
+// the session, see ClientSetting.PoolStats.
+func (s *Session) PoolStats() map[string]PoolStats {
+	t, _ := s.roundTripper().(*http.Transport)
+	return trackerOf(t).stats()
+}
+
+// CloseIdleConnections closes the idle connections of the transport of the
+// session, the connections in use are left open. It does not affect the
+// transport shared with Settings() unless the session uses it.
+func (s *Session) CloseIdleConnections() {
+	if t, ok := s.roundTripper().(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+		return
+	}
+
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+}
+
+// PoolStats describes the connections to a host.
+type PoolStats struct {
+	Open  int // connections open
+	InUse int // connections serving a request, whose response body is not read yet
+	Idle  int // connections waiting in the pool
+}
+
+// trackers holds the connTracker of the transports created by the
+// package.
+var trackers sync.Map // *http.Transport -> *connTracker
+
+// connTracker counts the connections dialed by a transport.
+type connTracker struct {
+	mu    sync.Mutex
+	hosts map[string]map[*trackedConn]struct{}
+
+	dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	dial        func(network, addr string) (net.Conn, error)
+}
+
+func trackerOf(t *http.Transport) *connTracker {
+	if t == nil {
+		return nil
+	}
+
+	if v, ok := trackers.Load(t); ok {
+		return v.(*connTracker)
+	}
+	return nil
+}
+
+// trackTransport wraps the dial functions of t to count its connections.
+// It must be called again whenever they are replaced.
+func trackTransport(t *http.Transport) {
+	v, _ := trackers.LoadOrStore(t, &connTracker{hosts: make(map[string]map[*trackedConn]struct{})})
+	ct := v.(*connTracker)
+
+	ct.dialContext, ct.dial = t.DialContext, t.Dial
+
+	switch {
+	case t.Dial != nil:
+		dial := t.Dial
+		t.Dial = func(network, addr string) (net.Conn, error) {
+			return ct.track(addr)(dial(network, addr))
+		}
+	case t.DialContext != nil:
+		dial := t.DialContext
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return ct.track(addr)(dial(ctx, network, addr))
+		}
+	default:
+		dial := (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return ct.track(addr)(dial(ctx, network, addr))
+		}
+	}
+}
+
+// untrack restores the dial functions of a copy of a tracked transport,
+// so the connections of the copy are not counted with the original ones.
+func untrack(copied, original *http.Transport) {
+	if ct := trackerOf(original); ct != nil {
+		copied.DialContext, copied.Dial = ct.dialContext, ct.dial
+	}
+}
+
+func (ct *connTracker) track(addr string) func(net.Conn, error) (net.Conn, error) {
+	return func(conn net.Conn, err error) (net.Conn, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		tc := &trackedConn{Conn: conn, tracker: ct, addr: addr}
+
+		ct.mu.Lock()
+		if ct.hosts[addr] == nil {
+			ct.hosts[addr] = make(map[*trackedConn]struct{})
+		}
+		ct.hosts[addr][tc] = struct{}{}
+		ct.mu.Unlock()
+
+		return tc, nil
+	}
+}
+
+func (ct *connTracker) stats() map[string]PoolStats {
+	stats := make(map[string]PoolStats)
+	if ct == nil {
+		return stats
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	for addr, conns := range ct.hosts {
+		var s PoolStats
+		for tc := range conns {
+			s.Open++
+			if tc.active > 0 {
+				s.InUse++
+			}
+		}
+		s.Idle = s.Open - s.InUse
+		stats[addr] = s
+	}
+
+	return stats
+}
+
+// trackedConn is a connection counted by a connTracker. active counts the
+// requests it serves, HTTP/2 connections serve several at once.
+type trackedConn struct {
+	net.Conn
+	tracker *connTracker
+	addr    string
+	active  int
+	once    sync.Once
+}
+
+func (c *trackedConn) acquire() {
+	c.tracker.mu.Lock()
+	c.active++
+	c.tracker.mu.Unlock()
+}
+
+func (c *trackedConn) release() {
+	c.tracker.mu.Lock()
+	if c.active > 0 {
+		c.active--
+	}
+	c.tracker.mu.Unlock()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() {
+		ct := c.tracker
+		ct.mu.Lock()
+		delete(ct.hosts[c.addr], c)
+		if len(ct.hosts[c.addr]) == 0 {
+			delete(ct.hosts, c.addr)
+		}
+		ct.mu.Unlock()
+	})
+
+	return c.Conn.Close()
+}
+
+// trackedConnOf returns the trackedConn under conn, such as the one under a
+// TLS connection.
+func trackedConnOf(conn net.Conn) *trackedConn {
+	for conn != nil {
+		if tc, ok := conn.(*trackedConn); ok {
+			return tc
+		}
+
+		nc, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return nil
+		}
+		conn = nc.NetConn()
+	}
+
+	return nil
+}
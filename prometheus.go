@@ -0,0 +1,224 @@
+package httpclient
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the latency histogram
+// buckets used when none are given to NewPrometheusMetrics.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics is a Metrics collecting the measurements in memory and
+// serving them in the Prometheus text exposition format:
+//
+//	m := httpclient.NewPrometheusMetrics("myapp", nil)
+//	httpclient.Settings().SetMetrics(m)
+//	http.Handle("/metrics", m)
+//
+// The exported metrics are <namespace>_requests_total,
+// <namespace>_request_duration_seconds, <namespace>_requests_in_flight,
+// <namespace>_request_bytes_total, <namespace>_response_bytes_total and
+// <namespace>_retries_total.
+type PrometheusMetrics struct {
+	namespace string
+	buckets   []float64
+
+	mu        sync.Mutex
+	requests  map[Labels]float64
+	durations map[Labels]*histogram
+	inFlight  map[Labels]float64
+	sent      map[Labels]float64
+	received  map[Labels]float64
+	retries   map[Labels]float64
+}
+
+type histogram struct {
+	counts []uint64 // 每个桶的计数，不累加
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheusMetrics returns a new instance of PrometheusMetrics. The
+// namespace defaults to "httpclient" and the buckets to DefaultBuckets.
+func NewPrometheusMetrics(namespace string, buckets []float64) *PrometheusMetrics {
+	if namespace == "" {
+		namespace = "httpclient"
+	}
+
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &PrometheusMetrics{
+		namespace: namespace,
+		buckets:   buckets,
+		requests:  make(map[Labels]float64),
+		durations: make(map[Labels]*histogram),
+		inFlight:  make(map[Labels]float64),
+		sent:      make(map[Labels]float64),
+		received:  make(map[Labels]float64),
+		retries:   make(map[Labels]float64),
+	}
+}
+
+// RequestStarted implements Metrics.
+func (p *PrometheusMetrics) RequestStarted(l Labels) {
+	p.mu.Lock()
+	p.inFlight[l]++
+	p.mu.Unlock()
+}
+
+// RequestDone implements Metrics.
+func (p *PrometheusMetrics) RequestDone(l Labels, d time.Duration, bytesSent int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.inFlight[Labels{Method: l.Method, Host: l.Host}]--
+	p.requests[l]++
+	p.sent[l] += float64(bytesSent)
+
+	h := p.durations[l]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(p.buckets))}
+		p.durations[l] = h
+	}
+
+	s := d.Seconds()
+	h.sum += s
+	h.count++
+
+	if i := sort.SearchFloat64s(p.buckets, s); i < len(p.buckets) {
+		h.counts[i]++
+	}
+}
+
+// ResponseRead implements Metrics.
+func (p *PrometheusMetrics) ResponseRead(l Labels, bytesReceived int64) {
+	p.mu.Lock()
+	p.received[l] += float64(bytesReceived)
+	p.mu.Unlock()
+}
+
+// Retried implements Metrics.
+func (p *PrometheusMetrics) Retried(l Labels) {
+	p.mu.Lock()
+	p.retries[l]++
+	p.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	bw := bufio.NewWriter(w)
+	p.write(bw)
+	bw.Flush()
+}
+
+func (p *PrometheusMetrics) write(w *bufio.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.writeFamily(w, "requests_total", "counter", "Number of requests sent.", p.requests)
+	p.writeFamily(w, "requests_in_flight", "gauge", "Number of requests waiting for a response.", p.inFlight)
+	p.writeFamily(w, "request_bytes_total", "counter", "Number of request body bytes sent.", p.sent)
+	p.writeFamily(w, "response_bytes_total", "counter", "Number of response body bytes received.", p.received)
+	p.writeFamily(w, "retries_total", "counter", "Number of retried requests.", p.retries)
+
+	name := p.namespace + "_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Latency of the requests until the response headers are received.\n", name)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+
+	for _, l := range sortedLabels(p.durations) {
+		h := p.durations[l]
+
+		var cumulative uint64
+		for i, le := range p.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(l, "le", formatFloat(le)), cumulative)
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(l, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, formatLabels(l), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(l), h.count)
+	}
+}
+
+func (p *PrometheusMetrics) writeFamily(w *bufio.Writer, name, typ, help string, values map[Labels]float64) {
+	name = p.namespace + "_" + name
+
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+
+	for _, l := range sortedLabels(values) {
+		fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(l), formatFloat(values[l]))
+	}
+}
+
+func sortedLabels[V any](m map[Labels]V) []Labels {
+	labels := make([]Labels, 0, len(m))
+	for l := range m {
+		labels = append(labels, l)
+	}
+
+	sort.Slice(labels, func(i, j int) bool {
+		a, b := labels[i], labels[j]
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		return a.Status < b.Status
+	})
+
+	return labels
+}
+
+// formatLabels formats the labels and the extra name, value pairs.
+func formatLabels(l Labels, extra ...string) string {
+	pairs := []string{"method", l.Method, "host", l.Host}
+	if l.Status != "" {
+		pairs = append(pairs, "status", l.Status)
+	}
+	pairs = append(pairs, extra...)
+
+	var b strings.Builder
+	b.WriteByte('{')
+
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+)
+
+// Protocol selects the HTTP versions used by the requests.
+type Protocol int
+
+// Protocols supported by the transports.
+const (
+	// ProtoHTTP2 uses HTTP/2 when the server selects it with ALPN during
+	// the TLS handshake and HTTP/1.1 otherwise, it is the default.
+	ProtoHTTP2 Protocol = iota + 1
+	// ProtoHTTP1 uses HTTP/1.1 only.
+	ProtoHTTP1
+	// ProtoH2C uses HTTP/2 only: with prior knowledge over cleartext (h2c)
+	// for http URLs, which suits internal services, and with ALPN for
+	// https URLs.
+	ProtoH2C
+)
+
+// String returns the name of the protocol.
+func (p Protocol) String() string {
+	switch p {
+	case ProtoHTTP1:
+		return "HTTP/1.1"
+	case ProtoH2C:
+		return "h2c"
+	}
+	return "HTTP/2.0"
+}
+
+// apply configures t for the protocol.
+func (p Protocol) apply(t *http.Transport) {
+	var protocols http.Protocols
+
+	switch p {
+	case ProtoHTTP1:
+		protocols.SetHTTP1(true)
+	case ProtoH2C:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	}
+
+	t.Protocols = &protocols
+	t.ForceAttemptHTTP2 = p != ProtoHTTP1
+
+	if p != ProtoHTTP1 {
+		return
+	}
+
+	// 复制来的 transport 可能已经配置了 h2，需要去掉
+	t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+
+	if t.TLSClientConfig != nil {
+		t.TLSClientConfig = t.TLSClientConfig.Clone()
+
+		var protos []string
+		for _, proto := range t.TLSClientConfig.NextProtos {
+			if proto != "h2" {
+				protos = append(protos, proto)
+			}
+		}
+		t.TLSClientConfig.NextProtos = protos
+	}
+}
+
+// parseProto returns the Protocol of an HTTP version such as "HTTP/1.1".
+func parseProto(proto string) (Protocol, int, int, error) {
+	switch proto {
+	case "HTTP/1.0", "HTTP/1.1":
+		major, minor, _ := http.ParseHTTPVersion(proto)
+		return ProtoHTTP1, major, minor, nil
+	case "HTTP/2", "HTTP/2.0", "h2":
+		return ProtoHTTP2, 2, 0, nil
+	case "h2c":
+		return ProtoH2C, 2, 0, nil
+	}
+
+	return 0, 0, 0, errors.New("request: invalid protocol version " + proto)
+}
+
+// SetProtocol selects the HTTP versions used by the requests. It applies to
+// the transports created by SetProxy and, when none was, to a copy of
+// http.DefaultTransport.
+func (c *ClientSetting) SetProtocol(p Protocol) *ClientSetting {
+	c.protocol = p
+	c.Proto = p.String()
+	c.ProtoMajor, c.ProtoMinor = 2, 0
+
+	if p == ProtoHTTP1 {
+		c.ProtoMajor, c.ProtoMinor = 1, 1
+	}
+
+	c.reinstall()
+	return c
+}
+
+// SetProtocol selects the HTTP versions used by the requests of the session
+// instead of the ones set by Settings().SetProtocol. A transport set with
+// SetTransport is used through a copy configured for the protocol, unless
+// it is not an *http.Transport, such as a mock.
+func (s *Session) SetProtocol(p Protocol) *Session {
+	s.transports.update(func(t *sessionTransport) { t.protocol = p })
+	return s
+}
+
+// Protocol returns the protocol of the response as an ALPN identifier:
+// "h2" for HTTP/2 over TLS, "h2c" for HTTP/2 over cleartext, and
+// "http/1.1" or "http/1.0" otherwise.
+func (r *Response) Protocol() string {
+	if r.ProtoMajor == 2 {
+		if r.TLS != nil {
+			return "h2"
+		}
+		return "h2c"
+	}
+
+	if r.ProtoMajor == 1 && r.ProtoMinor == 0 {
+		return "http/1.0"
+	}
+
+	return "http/1.1"
+}
@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSettingsSetProtocol(t *testing.T) {
+	s := Settings()
+	defer s.SetProtocol(s.protocol)
+
+	tests := []struct {
+		proto Protocol
+		h2    bool
+	}{
+		{ProtoHTTP1, false},
+		{ProtoHTTP2, true},
+		{ProtoHTTP1, false},
+		{ProtoH2C, true},
+	}
+
+	for _, tt := range tests {
+		before := s.ProxyTransport
+		s.SetProtocol(tt.proto)
+
+		tr := s.ProxyTransport
+		if tr == before {
+			t.Errorf("SetProtocol(%v) changed the transport in use", tt.proto)
+		}
+		if got := tr.Protocols.HTTP2(); got != tt.h2 {
+			t.Errorf("SetProtocol(%v): HTTP/2 enabled = %v, want %v", tt.proto, got, tt.h2)
+		}
+		if got := tr.TLSNextProto == nil || len(tr.TLSNextProto) > 0; got != tt.h2 {
+			t.Errorf("SetProtocol(%v): TLSNextProto allows h2 = %v, want %v", tt.proto, got, tt.h2)
+		}
+	}
+}
+
+func TestSettingsSetDialerAndPool(t *testing.T) {
+	s := Settings()
+	defer s.SetPool(s.pool)
+	defer s.SetDialer(s.dialer)
+
+	before := s.ProxyTransport
+	s.SetPool(PoolOptions{MaxIdleConns: 7})
+	if s.ProxyTransport == before || before.MaxIdleConns == 7 {
+		t.Error("SetPool changed the transport in use")
+	}
+	if got := s.ProxyTransport.MaxIdleConns; got != 7 {
+		t.Errorf("MaxIdleConns = %d, want 7", got)
+	}
+
+	before = s.ProxyTransport
+	s.SetDialer(&net.Dialer{})
+	if s.ProxyTransport == before {
+		t.Error("SetDialer changed the transport in use")
+	}
+	if got := s.ProxyTransport.MaxIdleConns; got != 7 {
+		t.Errorf("MaxIdleConns = %d after SetDialer, want 7", got)
+	}
+}
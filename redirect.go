@@ -0,0 +1,225 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors of the redirect policies, returned by Execute wrapped in a
+// *url.Error. The request is not retried after them.
+var (
+	ErrTooManyRedirects  = errors.New("request: exceed max redirects")
+	ErrRedirectHost      = errors.New("request: redirect to another host refused")
+	ErrRedirectDowngrade = errors.New("request: redirect from https to http refused")
+)
+
+// AuthForwarding selects the redirects the Authorization header is
+// forwarded to.
+type AuthForwarding int
+
+// Authorization forwarding rules.
+const (
+	// ForwardAuthSameDomain forwards it to the host of the request and to
+	// its subdomains, as net/http does. It is the default.
+	ForwardAuthSameDomain AuthForwarding = iota
+	// ForwardAuthSameHost forwards it to the host of the request only.
+	ForwardAuthSameHost
+	// ForwardAuthAlways forwards it to any host.
+	ForwardAuthAlways
+	// ForwardAuthNever never forwards it.
+	ForwardAuthNever
+)
+
+// maxRedirectBody limits the body of the intermediate responses kept by
+// Response.History.
+const maxRedirectBody = 64 << 10
+
+// RedirectPolicy controls how the redirects of a request are followed. Its
+// zero value follows them as net/http does.
+type RedirectPolicy struct {
+	// NoFollow returns the redirect response instead of following it.
+	NoFollow bool
+	// Max caps the length of the redirect chain, the original request
+	// included, like Redirects: 0 means 10 and a negative value refuses
+	// every redirect.
+	Max int
+	// SameHost refuses the redirects to another host than the one of the
+	// original request.
+	SameHost bool
+	// RefuseDowngrade refuses the redirects from https to http.
+	RefuseDowngrade bool
+	// PreserveMethod keeps the method and the body of the request on 301
+	// and 302 redirects, which are otherwise followed with a GET like 303
+	// ones. When the body cannot be replayed the redirect response is
+	// returned instead.
+	PreserveMethod bool
+	// Authorization selects the redirects the Authorization header is
+	// forwarded to.
+	Authorization AuthForwarding
+	// StripHeaders are headers not forwarded to another host, such as the
+	// ones carrying API keys. Authorization, Cookie and the other
+	// sensitive headers are handled by net/http.
+	StripHeaders []string
+}
+
+// SetRedirectPolicy sets the default redirect policy of the requests.
+func (c *ClientSetting) SetRedirectPolicy(p RedirectPolicy) *ClientSetting {
+	c.redirect = &p
+	return c
+}
+
+// SetRedirectPolicy sets the redirect policy of the requests of the session
+// instead of the one set by Settings().SetRedirectPolicy.
+func (s *Session) SetRedirectPolicy(p RedirectPolicy) *Session {
+	s.redirect = &p
+	return s
+}
+
+// SetRedirectPolicy sets the redirect policy of the request instead of the
+// one of the session and of Settings().
+func (c *Client) SetRedirectPolicy(p RedirectPolicy) *Client {
+	c.redirect = &p
+	return c
+}
+
+// Redirects sets the max redirects count for the request.
+// If not set, request will use its default policy,
+// which is to stop after 10 consecutive requests.
+func (c *Client) Redirects(count int) *Client {
+	p := c.redirectPolicy()
+	if count <= 0 {
+		count = -1
+	}
+
+	p.Max = count
+	c.redirect = &p
+
+	return c
+}
+
+// redirectPolicy returns the redirect policy of the request.
+func (c *Client) redirectPolicy() RedirectPolicy {
+	switch {
+	case c.redirect != nil:
+		return *c.redirect
+	case c.session != nil && c.session.redirect != nil:
+		return *c.session.redirect
+	case Settings().redirect != nil:
+		return *Settings().redirect
+	}
+
+	return RedirectPolicy{}
+}
+
+// checkRedirect applies the redirect policy of the request to req, the next
+// request of the redirect chain via, and records the redirect response in
+// the history of the request.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	p := c.redirectPolicy()
+
+	if p.NoFollow {
+		return http.ErrUseLastResponse
+	}
+
+	max := p.Max
+	if max == 0 {
+		max = 10
+	}
+
+	if max < 0 || len(via) >= max {
+		return ErrTooManyRedirects
+	}
+
+	first, prev := via[0], via[len(via)-1]
+	sameHost := strings.EqualFold(req.URL.Host, first.URL.Host)
+
+	if p.SameHost && !sameHost {
+		return ErrRedirectHost
+	}
+
+	if p.RefuseDowngrade && prev.URL.Scheme == "https" && req.URL.Scheme == "http" {
+		return ErrRedirectDowngrade
+	}
+
+	if p.PreserveMethod && req.Method != prev.Method {
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusFound:
+			if err := preserveMethod(req, prev, first); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch p.Authorization {
+	case ForwardAuthSameHost:
+		if !sameHost {
+			req.Header.Del("Authorization")
+		}
+	case ForwardAuthAlways:
+		if auth, ok := first.Header["Authorization"]; ok {
+			req.Header["Authorization"] = auth
+		}
+	case ForwardAuthNever:
+		req.Header.Del("Authorization")
+	}
+
+	if !sameHost {
+		for _, h := range p.StripHeaders {
+			req.Header.Del(h)
+		}
+	}
+
+	c.history = append(c.history, keepRedirect(req.Response))
+
+	return nil
+}
+
+// preserveMethod sends req with the method of prev and the body of the
+// original request first, http.ErrUseLastResponse is returned when the body
+// cannot be replayed.
+func preserveMethod(req, prev, first *http.Request) error {
+	if first.GetBody == nil {
+		if first.ContentLength != 0 || (first.Body != nil && first.Body != http.NoBody) {
+			return http.ErrUseLastResponse
+		}
+	} else {
+		body, err := first.GetBody()
+		if err != nil {
+			return err
+		}
+
+		req.Body, req.GetBody, req.ContentLength = body, first.GetBody, first.ContentLength
+	}
+
+	req.Method = prev.Method
+
+	// net/http 转成 GET 时去掉了描述请求体的头
+	for _, h := range []string{"Content-Type", "Content-Encoding", "Content-Language", "Content-Location"} {
+		if v, ok := first.Header[h]; ok {
+			req.Header[h] = v
+		}
+	}
+
+	return nil
+}
+
+// keepRedirect returns a copy of the redirect response res holding the
+// beginning of its body, since net/http closes it.
+func keepRedirect(res *http.Response) *Response {
+	hop := *res
+
+	b, _ := io.ReadAll(io.LimitReader(res.Body, maxRedirectBody))
+	hop.Body = io.NopCloser(bytes.NewReader(b))
+
+	return &Response{Response: &hop}
+}
+
+// History returns the redirect responses received before the response,
+// the first one first. Their body holds at most the first 64 KiB of the
+// body received. It is empty when the request was not redirected.
+func (r *Response) History() []*Response {
+	return r.history
+}
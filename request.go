@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptrace"
 	"net/http/httputil"
 	"net/textproto"
 	"net/url"
@@ -36,15 +38,6 @@ func (e ErrStatusNotOk) Error() string {
 	return fmt.Sprintf("request: status code is not ok (>= 400).code=%d", e.statusCode)
 }
 
-type maxRedirects int
-
-func (mr maxRedirects) check(req *http.Request, via []*http.Request) error {
-	if len(via) >= int(mr) {
-		return fmt.Errorf("request: exceed max redirects")
-	}
-	return nil
-}
-
 type basicAuthInfo struct {
 	name     string
 	password string
@@ -52,42 +45,65 @@ type basicAuthInfo struct {
 
 // Client is a HTTP client which provides usable and chainable methods.
 type Client struct {
-	cli       *http.Client
-	req       *http.Request
-	res       *Response
-	method    string
-	url       *url.URL
-	queryVals url.Values
-	formVals  url.Values
-	mw        *multipart.Writer
-	mwBuf     *bytes.Buffer
-	body      io.Reader
-	basicAuth *basicAuthInfo
-	header    http.Header
-	cookies   []*http.Cookie
-	timeout   time.Duration
-	redirects maxRedirects
-	err       error
+	cli           *http.Client
+	req           *http.Request
+	res           *Response
+	ctx           context.Context
+	session       *Session
+	method        string
+	url           *url.URL
+	template      string
+	pathParams    map[string]interface{}
+	queryVals     url.Values
+	formVals      url.Values
+	multipart     bool
+	files         []attachment
+	body          io.Reader
+	basicAuth     *basicAuthInfo
+	header        http.Header
+	cookies       []*http.Cookie
+	timeouts      Timeouts
+	redirect      *RedirectPolicy
+	compression   *Compression
+	validators    *ValidatorStore
+	validatorsSet bool
+	history       []*Response
+	retries       int
+	backoff       *RetryBackoff
+	err           error
 }
 
 // New returns a new instance of Client.
 func New() *Client {
 	c := &Client{
-		cli:      new(http.Client),
-		header:   make(http.Header),
-		formVals: make(url.Values),
-		cookies:  make([]*http.Cookie, 0),
-		mwBuf:    bytes.NewBuffer(nil),
+		cli:       new(http.Client),
+		header:    make(http.Header),
+		queryVals: make(url.Values),
+		formVals:  make(url.Values),
+		cookies:   make([]*http.Cookie, 0),
+		retries:   Settings().Retries,
 	}
-	c.mw = multipart.NewWriter(c.mwBuf)
+	c.cli.CheckRedirect = c.checkRedirect
 
 	return c
 }
 
 // To defines the method and URL of the request.
+//
+// URL may be an RFC 6570 URI template such as
+// "/users/{id}/repos{?page,per_page}", it is expanded with the values given
+// by PathParam and PathParams when the request is sent, and the expanded
+// query is merged with the values added by AddQuery and SetQuery. Relative
+// URLs are resolved against the base URL of the session, if any.
 func (c *Client) To(method string, URL string) *Client {
 	c.method = method
-	u, err := url.Parse(URL)
+
+	if strings.ContainsAny(URL, "{}") {
+		c.template = URL
+		return c
+	}
+
+	u, err := c.parseURL(URL)
 
 	if err != nil {
 		c.err = err
@@ -100,6 +116,67 @@ func (c *Client) To(method string, URL string) *Client {
 	return c
 }
 
+// PathParam sets the value of the URI template variable key. The value may be
+// a string, a []string or a map[string]string, other values are formatted
+// with fmt.Sprint. Values are escaped according to the template expression
+// they are used in, so "a/b" becomes "a%2Fb" in "/users/{id}".
+func (c *Client) PathParam(key string, value interface{}) *Client {
+	if c.pathParams == nil {
+		c.pathParams = make(map[string]interface{})
+	}
+
+	c.pathParams[key] = value
+	return c
+}
+
+// PathParams sets the values of several URI template variables at once.
+func (c *Client) PathParams(params map[string]interface{}) *Client {
+	for k, v := range params {
+		c.PathParam(k, v)
+	}
+
+	return c
+}
+
+func (c *Client) parseURL(rawurl string) (*url.URL, error) {
+	u, err := url.Parse(rawurl)
+
+	if err != nil || c.session == nil {
+		return u, err
+	}
+
+	return c.session.resolve(u), nil
+}
+
+// expand expands the URI template given to To, if any.
+func (c *Client) expand() error {
+	if c.template == "" || c.url != nil {
+		return nil
+	}
+
+	rawurl, err := expandURITemplate(c.template, c.pathParams)
+
+	if err != nil {
+		return err
+	}
+
+	u, err := c.parseURL(rawurl)
+
+	if err != nil {
+		return err
+	}
+
+	q := u.Query()
+	for k, vs := range c.queryVals {
+		for _, v := range vs {
+			q.Add(k, v)
+		}
+	}
+
+	c.url, c.queryVals = u, q
+	return nil
+}
+
 // SetHeader sets the request header entries associated with key to the single
 // element value. It replaces any existing values associated with key.
 func (c *Client) SetHeader(key, value string) *Client {
@@ -198,7 +275,7 @@ func (c *Client) SetQuery(vals url.Values) *Client {
 // SendBody sends the body in JSON format, body can be anything which can be
 // Marshaled or just Marshaled JSON string.
 func (c *Client) SendBody(body interface{}) *Client {
-	if c.body != nil || c.mwBuf.Len() != 0 {
+	if c.body != nil || len(c.files) != 0 {
 		c.err = ErrBodyAlreadySet
 		return c
 	}
@@ -252,21 +329,32 @@ func (c *Client) AddCookieJar(jar http.CookieJar) *Client {
 }
 
 // SetTimeout specifies a time limit for the request.
-// The timeout includes connection time, any
+// The timeout includes connection time, any retries and
 // redirects, and reading the response body. The timer remains
-// running after Get, Head, Post, or End return and will
-// interrupt reading of the response body.
+// running after Execute returns and will interrupt reading of
+// the response body. SetTimeouts sets the limits of each phase.
 func (c *Client) SetTimeout(timeout time.Duration) *Client {
-	c.cli.Timeout = timeout
+	c.timeouts.Total = timeout
 
 	return c
 }
 
-// Redirects sets the max redirects count for the request.
-// If not set, request will use its default policy,
-// which is to stop after 10 consecutive requests.
-func (c *Client) Redirects(count int) *Client {
-	c.cli.CheckRedirect = maxRedirects(count).check
+// SetContext sets the context of the request, which cancels it and carries
+// the parent span of the request when a Tracer is set.
+func (c *Client) SetContext(ctx context.Context) *Client {
+	c.ctx = ctx
+
+	return c
+}
+
+// SetRetries sets how many times the request is retried when it fails before
+// a response is received, -1 means it is retried until its context is done.
+// It defaults to Settings().Retries, which is 0. Each retry waits for the
+// delay set by SetRetryBackoff. Requests whose body cannot be replayed are
+// never retried, nor are the ones whose method is not idempotent, such as
+// POST, unless they carry an idempotency key (see SetIdempotencyKey).
+func (c *Client) SetRetries(n int) *Client {
+	c.retries = n
 
 	return c
 }
@@ -307,75 +395,44 @@ func (c *Client) AddField(key, value string) *Client {
 	return c
 }
 
+// SetMultipart sends the form fields as "multipart/form-data" even when no
+// file is attached.
+func (c *Client) SetMultipart() *Client {
+	c.multipart = true
+	return c
+}
+
 // AttachFile adds the attachment file to the form. Once the attachment was
 // set, the "Content-Type" will be set to "multipart/form-data; boundary=xxx"
-// automatically.
+// automatically. The file is streamed while the request is sent.
 func (c *Client) AttachFile(fieldname, filePath, filename string) *Client {
-	if c.body != nil {
-		c.err = ErrBodyAlreadySet
-		return c
-	}
-
-	file, err := os.Open(filePath)
-
-	if err != nil {
-		c.err = err
-		return c
-	}
-
-	if filename == "" {
-		filename = path.Base(filePath)
-	}
-
-	fw, err := c.mw.CreateFormFile(fieldname, filename)
-
-	if err != nil {
-		c.err = err
-		return c
-	}
-
-	if _, err = io.Copy(fw, file); err != nil {
-		c.err = err
-		return c
-	}
-
-	return c
+	return c.attach(attachment{field: fieldname, path: filePath, filename: filename})
 }
 
 // AttachFileRange adds the attachment file to the form. Once the attachment was
 // set, the "Content-Type" will be set to "multipart/form-data; boundary=xxx"
-// automatically.
+// automatically. The range is streamed while the request is sent.
 func (c *Client) AttachFileRange(fieldname, filePath, filename string, start, length int64) *Client {
+	return c.attach(attachment{field: fieldname, path: filePath, filename: filename, ranged: true, start: start, length: length})
+}
+
+func (c *Client) attach(a attachment) *Client {
 	if c.body != nil {
 		c.err = ErrBodyAlreadySet
 		return c
 	}
 
-	file, err := os.Open(filePath)
-
-	file.Seek(start, 0)
-
-	if err != nil {
-		c.err = err
-		return c
-	}
-
-	if filename == "" {
-		filename = path.Base(filePath)
-	}
-
-	fw, err := c.mw.CreateFormFile(fieldname, filename)
-
-	if err != nil {
+	// 文件在发送时才读取，这里只检查它是否存在
+	if _, err := os.Stat(a.path); err != nil {
 		c.err = err
 		return c
 	}
 
-	if _, err = io.CopyN(fw, file, length); err != nil {
-		c.err = err
-		return c
+	if a.filename == "" {
+		a.filename = path.Base(a.path)
 	}
 
+	c.files = append(c.files, a)
 	return c
 }
 
@@ -385,6 +442,11 @@ func (c *Client) AttachFileRange(fieldname, filePath, filename string, start, le
 // failure to speak HTTP (such as a network connectivity problem), or generated
 // by former chained methods. A non-2xx status code doesn't cause an error.
 func (c *Client) Execute() (*Response, error) {
+	if err := c.expand(); err != nil {
+		c.err = err
+		return nil, err
+	}
+
 	if c.url == nil {
 		return nil, ErrLackURL
 	}
@@ -402,21 +464,177 @@ func (c *Client) Execute() (*Response, error) {
 		return nil, err
 	}
 
-	response, err := c.cli.Do(c.req)
+	span := c.startSpan()
+
+	trace := newClientTrace()
+	c.req = c.req.WithContext(httptrace.WithClientTrace(c.req.Context(), trace.clientTrace()))
+
+	var guard *timeoutGuard
+	if timeouts := c.resolvedTimeouts(); timeouts.enabled() {
+		guard = newTimeoutGuard(c.req.Context(), timeouts)
+	}
+
+	metrics := c.metrics()
+	labels := Labels{Method: c.req.Method, Host: c.req.URL.Host}
+
+	response, err := c.do(guard, metrics, span, labels)
 
 	if err != nil {
+		if guard != nil {
+			guard.finish()
+		}
+		trace.release()
+		span.RecordError(err)
+		span.End()
+
 		c.err = err
 		return nil, err
 	}
 
-	c.res = &Response{Response: response}
+	span.SetAttributes(Attribute{"http.response.status_code", response.StatusCode})
+	if response.StatusCode >= 400 {
+		span.RecordError(ErrStatusNotOk{statusCode: response.StatusCode})
+	}
+
+	if guard != nil {
+		response.Body = &timeoutBody{ReadCloser: response.Body, guard: guard}
+	}
+
+	labels.Status = statusClass(response, nil)
+	body := &traceBody{ReadCloser: response.Body, trace: trace}
+	saveValidators := c.storeValidators(response)
+	body.onDone = func(n int64, eof bool) {
+		if eof && saveValidators != nil {
+			saveValidators()
+		}
+		trace.release()
+		metrics.ResponseRead(labels, n)
+		span.End()
+	}
+	response.Body = body
+
+	c.res = &Response{Response: response, trace: trace, history: c.history}
 
 	return c.res, nil
 }
 
+// do sends the request, retrying it as configured by SetRetries when no
+// response is received, after the delay set by SetRetryBackoff. Each
+// attempt is limited by the timeouts of guard, if any.
+func (c *Client) do(guard *timeoutGuard, metrics Metrics, span Span, labels Labels) (*http.Response, error) {
+	sent := c.req.ContentLength
+	if sent < 0 {
+		sent = 0
+	}
+
+	logging := c.logging()
+	logging.request(c.req)
+
+	for attempt := 1; ; attempt++ {
+		metrics.RequestStarted(labels)
+
+		if guard != nil {
+			c.req = c.req.WithContext(guard.newAttempt())
+		}
+		c.history = nil
+
+		start := time.Now()
+		response, err := c.cli.Do(c.req)
+		elapsed := time.Since(start)
+
+		if err != nil && guard != nil {
+			err = guard.wrap(err)
+		}
+
+		done := labels
+		done.Status = statusClass(response, err)
+		metrics.RequestDone(done, elapsed, sent)
+
+		if err == nil {
+			logging.response(c.req, response, attempt, elapsed)
+			return response, nil
+		}
+
+		if !c.rewind(guard, attempt, err) {
+			logging.failure(c.req, attempt, elapsed, err)
+			return nil, err
+		}
+
+		logging.retry(c.req, attempt, err)
+		metrics.Retried(labels)
+		span.AddEvent("retry", Attribute{"http.request.resend_count", attempt}, Attribute{"exception.message", err.Error()})
+
+		ctx := c.req.Context()
+		if guard != nil {
+			ctx = guard.ctx
+		}
+
+		// 退避期间可以通过 context 取消
+		if werr := wait(ctx, c.retryBackoff().delay(attempt)); werr != nil {
+			err = &url.Error{Op: c.req.Method[:1] + strings.ToLower(c.req.Method[1:]), URL: c.req.URL.String(), Err: werr}
+			if guard != nil {
+				err = guard.wrap(err)
+			}
+
+			logging.failure(c.req, attempt, elapsed, err)
+			return nil, err
+		}
+	}
+}
+
+// rewind reports whether the request can be sent again after attempt
+// failed with err, and resets its body. An attempt which exceeded the
+// timeout of a phase may be retried, not one which exceeded the total
+// timeout or was refused by the redirect policy, nor a request which is
+// not idempotent and has no idempotency key.
+func (c *Client) rewind(guard *timeoutGuard, attempt int, err error) bool {
+	if c.retries >= 0 && attempt > c.retries {
+		return false
+	}
+
+	if errors.Is(err, ErrTooManyRedirects) || errors.Is(err, ErrRedirectHost) || errors.Is(err, ErrRedirectDowngrade) {
+		return false
+	}
+
+	// 非幂等的请求可能已被处理，只有带幂等键时才能重发
+	if !idempotent(c.req.Method) && c.req.Header.Get(IdempotencyKeyHeader) == "" {
+		return false
+	}
+
+	ctx := c.req.Context()
+	if guard != nil {
+		ctx = guard.ctx
+	}
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if c.req.Body == nil || c.req.Body == http.NoBody {
+		return true
+	}
+
+	if c.req.GetBody == nil {
+		return false
+	}
+
+	body, err := c.req.GetBody()
+	if err != nil {
+		return false
+	}
+
+	c.req.Body = body
+	return true
+}
+
 // Req returns the representing http.Request instance of this request.
 // It is often used in wirting tests.
 func (c *Client) Req() (*http.Request, error) {
+	if err := c.expand(); err != nil {
+		c.err = err
+		return nil, err
+	}
+
 	if c.url == nil {
 		return nil, ErrLackURL
 	}
@@ -456,6 +674,16 @@ func (c *Client) JSON(v ...interface{}) (interface{}, error) {
 	return c.res.JSON(v...)
 }
 
+// Decode sends the HTTP request and decodes the JSON response body into v.
+// See Response.Decode for how errv is used.
+func (c *Client) Decode(v interface{}, errv interface{}) error {
+	if _, err := c.Execute(); err != nil {
+		return err
+	}
+
+	return c.res.Decode(v, errv)
+}
+
 // Text sends the HTTP request and returns the response body with text format.
 func (c *Client) Text() (string, error) {
 	if _, err := c.Execute(); err != nil {
@@ -513,11 +741,6 @@ func (c *Client) ToFile(dir, fileName string) error {
 		}
 	}
 
-	contentLength := c.res.Header.Get("Content-Length")
-
-	fmt.Printf("header=%+v\n", c.res.Header)
-	fmt.Printf("fileName=%+v, contentLength=%v\n", fileName, contentLength)
-
 	filePath := path.Join(dir, fileName)
 
 	file, err := os.Create(filePath)
@@ -533,42 +756,53 @@ func (c *Client) ToFile(dir, fileName string) error {
 func (c *Client) assemble() error {
 	c.url.RawQuery = c.queryVals.Encode()
 
-	if Settings().UserAgent != "" {
+	if Settings().UserAgent != "" && c.header.Get("User-Agent") == "" {
 		c.SetHeader("User-Agent", Settings().UserAgent)
 	}
 
-	if Settings().ProxyTransport != nil {
+	c.attachIdempotencyKey()
+
+	if c.session != nil {
+		c.cli.Transport = c.session.roundTripper()
+		if c.session.har != nil {
+			c.cli.Transport = &harTransport{rt: c.cli.Transport, h: c.session.har}
+		}
+	} else if Settings().ProxyTransport != nil {
 		c.cli.Transport = Settings().ProxyTransport
 	}
 
-	var buf io.Reader
+	var (
+		buf      io.Reader
+		boundary string
+	)
 
-	if c.mwBuf.Len() != 0 {
-		if c.formVals != nil {
-			for k, vs := range c.formVals {
-				for _, v := range vs {
-					if err := c.mw.WriteField(k, v); err != nil {
-						return err
-					}
-				}
-			}
-		}
-
-		buf = c.mwBuf
-		c.SetContentType(c.mw.FormDataContentType())
-		c.mw.Close()
+	if len(c.files) != 0 || c.multipart {
+		mw := multipart.NewWriter(nil)
+		boundary = mw.Boundary()
+		c.SetContentType(mw.FormDataContentType())
 	} else if c.formVals != nil && c.body == nil {
 		buf = strings.NewReader(c.formVals.Encode())
 	} else {
 		buf = c.body
 	}
 
-	req, err := http.NewRequest(c.method, c.url.String(), buf)
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.method, c.url.String(), buf)
 
 	if err != nil {
 		return err
 	}
 
+	if boundary != "" {
+		if err := c.assembleMultipart(req, boundary); err != nil {
+			return err
+		}
+	}
+
 	c.req = req
 	c.req.Header = c.header
 
@@ -580,7 +814,9 @@ func (c *Client) assemble() error {
 		c.req.AddCookie(cookie)
 	}
 
-	return nil
+	c.addValidators()
+
+	return c.compress()
 }
 
 // URLString returns the url string
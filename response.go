@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // Response represents the response from a HTTP request.
@@ -20,6 +21,26 @@ type Response struct {
 
 	raw     *bytes.Buffer
 	content []byte
+	trace   *clientTrace
+	history []*Response
+}
+
+// Timings is the breakdown of the time spent on a request. When the request
+// was redirected, the phases describe the last hop while TimeToFirstByte and
+// Total are measured from the start of the first one.
+type Timings struct {
+	DNSLookup        time.Duration // 域名解析
+	TCPConnection    time.Duration // 建立 TCP 连接
+	TLSHandshake     time.Duration // TLS 握手
+	ServerProcessing time.Duration // 请求发送完毕到收到首字节
+	TimeToFirstByte  time.Duration // 开始请求到收到首字节
+	ContentTransfer  time.Duration // 接收响应体
+	Total            time.Duration
+
+	Reused     bool          // the connection was reused from the pool
+	WasIdle    bool          // the reused connection was idle
+	IdleTime   time.Duration // how long the reused connection was idle
+	RemoteAddr string
 }
 
 // Raw returns the raw bytes body of the response.
@@ -132,6 +153,34 @@ func (r *Response) JSON(v ...interface{}) (interface{}, error) {
 	return res, nil
 }
 
+// Decode decodes the JSON response body into v, v may be nil to discard the
+// body. Unlike JSON, the "Content-Type" of the response is not checked.
+//
+// If the response status code is not ok, the body is decoded into errv
+// instead, which is returned when it implements error. Otherwise an
+// ErrStatusNotOk is returned.
+func (r *Response) Decode(v interface{}, errv interface{}) error {
+	b, err := r.Content()
+	if err != nil {
+		return err
+	}
+
+	if !r.OK() {
+		if errv != nil && len(b) > 0 && json.Unmarshal(b, errv) == nil {
+			if e, ok := errv.(error); ok {
+				return e
+			}
+		}
+		return ErrStatusNotOk{statusCode: r.StatusCode}
+	}
+
+	if v == nil || len(b) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(b, v)
+}
+
 // Text returns the response body with text format.
 func (r *Response) Text() (string, error) {
 	b, err := r.Content()
@@ -167,6 +216,48 @@ func (r *Response) URL() (*url.URL, error) {
 	return u, nil
 }
 
+// Timings returns the timing breakdown of the request. ContentTransfer and
+// Total only cover the response body once it has been read to the end or
+// closed, which Raw, Content, JSON and Text do.
+func (r *Response) Timings() Timings {
+	if r.trace == nil {
+		return Timings{}
+	}
+
+	t := r.trace.snapshot()
+	since := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return to.Sub(from)
+	}
+
+	connectDone := t.connectDone
+	if !t.tlsStart.IsZero() && t.tlsStart.Before(connectDone) {
+		// ConnectDone fires after the TLS handshake when a proxy is used
+		connectDone = t.tlsStart
+	}
+
+	end := t.bodyDone
+	if end.IsZero() {
+		end = t.firstByte
+	}
+
+	return Timings{
+		DNSLookup:        since(t.dnsStart, t.dnsDone),
+		TCPConnection:    since(t.connectStart, connectDone),
+		TLSHandshake:     since(t.tlsStart, t.tlsDone),
+		ServerProcessing: since(t.wroteRequest, t.firstByte),
+		TimeToFirstByte:  since(t.start, t.firstByte),
+		ContentTransfer:  since(t.firstByte, t.bodyDone),
+		Total:            since(t.start, end),
+		Reused:           t.reused,
+		WasIdle:          t.wasIdle,
+		IdleTime:         t.idleTime,
+		RemoteAddr:       t.remoteAddr,
+	}
+}
+
 // Reason returns the status text of the response status code.
 func (r *Response) Reason() string {
 	return http.StatusText(r.StatusCode)
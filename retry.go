@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryBackoff is the delay before each retry of a request. The n-th retry
+// waits between half and all of Min * 2^(n-1), capped to Max, the random
+// part spreading the retries of concurrent requests.
+type RetryBackoff struct {
+	Min time.Duration // 0 retries at once
+	Max time.Duration // 0 means DefaultRetryBackoff.Max
+}
+
+// DefaultRetryBackoff is the retry delay used until SetRetryBackoff is
+// called.
+var DefaultRetryBackoff = RetryBackoff{Min: 100 * time.Millisecond, Max: 10 * time.Second}
+
+// SetRetryBackoff sets the default delay before the retries of the
+// requests.
+func (c *ClientSetting) SetRetryBackoff(b RetryBackoff) *ClientSetting {
+	c.backoff = &b
+	return c
+}
+
+// SetRetryBackoff sets the delay before the retries of the requests of the
+// session instead of the one set by Settings().SetRetryBackoff.
+func (s *Session) SetRetryBackoff(b RetryBackoff) *Session {
+	s.backoff = &b
+	return s
+}
+
+// SetRetryBackoff sets the delay before the retries of the request instead
+// of the one of the session and of Settings().
+func (c *Client) SetRetryBackoff(b RetryBackoff) *Client {
+	c.backoff = &b
+	return c
+}
+
+// retryBackoff returns the retry delay of the request.
+func (c *Client) retryBackoff() RetryBackoff {
+	switch {
+	case c.backoff != nil:
+		return *c.backoff
+	case c.session != nil && c.session.backoff != nil:
+		return *c.session.backoff
+	case Settings().backoff != nil:
+		return *Settings().backoff
+	}
+
+	return DefaultRetryBackoff
+}
+
+// delay returns the delay before the retry following attempt.
+func (b RetryBackoff) delay(attempt int) time.Duration {
+	if b.Min <= 0 {
+		return 0
+	}
+
+	max := b.Max
+	if max <= 0 {
+		max = DefaultRetryBackoff.Max
+	}
+
+	d := b.Min
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// wait sleeps for d unless ctx is done first, then it returns its error.
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
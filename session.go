@@ -0,0 +1,159 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Session holds the configuration shared by all requests created from it,
+// such as the base URL of an API and its default headers.
+type Session struct {
+	baseURL         *url.URL
+	header          http.Header
+	transport       http.RoundTripper
+	transports      sessionTransport
+	timeouts        Timeouts
+	redirect        *RedirectPolicy
+	idempotencyKeys func() string
+	compression     *Compression
+	validators      *ValidatorStore
+	backoff         *RetryBackoff
+	har             *HARRecorder
+	metrics         Metrics
+	tracing         *tracing
+	logging         *logging
+	err             error
+}
+
+// NewSession returns a new instance of Session.
+func NewSession() *Session {
+	return &Session{
+		header: make(http.Header),
+	}
+}
+
+// SetBaseURL sets the base URL which relative request URLs are resolved
+// against. Unlike url.ResolveReference, the path of the request is appended
+// to the path of the base URL, so with a base URL of
+// "https://api.example.com/v3" the request "/users" is sent to
+// "https://api.example.com/v3/users".
+func (s *Session) SetBaseURL(rawurl string) *Session {
+	u, err := url.Parse(rawurl)
+
+	if err != nil {
+		s.err = err
+		return s
+	}
+
+	s.baseURL = u
+	return s
+}
+
+// BaseURL returns the base URL of the session.
+func (s *Session) BaseURL() *url.URL {
+	return s.baseURL
+}
+
+// SetHeader sets the default header entries associated with key to the
+// single element value. Every request created by the session gets a copy.
+func (s *Session) SetHeader(key, value string) *Session {
+	s.header.Set(key, value)
+	return s
+}
+
+// AddHeader adds the key, value pair to the default headers of the session.
+func (s *Session) AddHeader(key, value string) *Session {
+	s.header.Add(key, value)
+	return s
+}
+
+// SetTransport sets the http.RoundTripper used by the requests of the
+// session instead of the transport configured by Settings().SetProxy. It is
+// often used to plug in a mock.Transport in tests.
+func (s *Session) SetTransport(rt http.RoundTripper) *Session {
+	s.transport = rt
+	return s
+}
+
+// SetHARRecorder records every exchange of the session into h.
+func (s *Session) SetHARRecorder(h *HARRecorder) *Session {
+	s.har = h
+	return s
+}
+
+// SetMetrics sets the Metrics receiving the measurements of the requests of
+// the session instead of the one set by Settings().SetMetrics.
+func (s *Session) SetMetrics(m Metrics) *Session {
+	s.metrics = m
+	return s
+}
+
+// SetTracer sets the Tracer and propagators of the requests of the session
+// instead of the ones set by Settings().SetTracer.
+func (s *Session) SetTracer(t Tracer, propagators ...Propagator) *Session {
+	s.tracing = newTracing(t, propagators)
+	return s
+}
+
+// SetLogger sets the Logger of the requests of the session instead of the
+// one set by Settings().SetLogger.
+func (s *Session) SetLogger(l Logger, opts LogOptions) *Session {
+	s.logging = newLogging(l, opts)
+	return s
+}
+
+// New returns a new Client bound to the session.
+func (s *Session) New() *Client {
+	c := New()
+	c.session = s
+	c.err = s.err
+
+	for k, vs := range s.header {
+		c.header[k] = append([]string(nil), vs...)
+	}
+
+	return c
+}
+
+// To returns a new Client bound to the session with the method and URL of
+// the request defined. See Client.To for the supported URL forms.
+func (s *Session) To(method, URL string) *Client {
+	return s.New().To(method, URL)
+}
+
+// resolve joins ref with the base URL of the session. Absolute URLs are
+// returned unchanged.
+func (s *Session) resolve(ref *url.URL) *url.URL {
+	if s.baseURL == nil || ref.IsAbs() || ref.Host != "" {
+		return ref
+	}
+
+	u := *s.baseURL
+	u.Fragment = ref.Fragment
+
+	if ref.Path != "" {
+		p := strings.TrimSuffix(s.baseURL.EscapedPath(), "/") + "/" +
+			strings.TrimPrefix(ref.EscapedPath(), "/")
+
+		if unescaped, err := url.PathUnescape(p); err == nil {
+			u.Path, u.RawPath = unescaped, p
+		}
+	}
+
+	switch {
+	case ref.RawQuery == "":
+	case u.RawQuery == "":
+		u.RawQuery = ref.RawQuery
+	default:
+		q := u.Query()
+		for k, vs := range ref.Query() {
+			for _, v := range vs {
+				q.Add(k, v)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	return &u
+}
@@ -0,0 +1,375 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStopEvents may be returned by the callback given to ReadEvents and
+// Listen to stop reading the events without error.
+var ErrStopEvents = errors.New("request: stop reading events")
+
+// Event is a server-sent event.
+type Event struct {
+	ID    string // last event ID, also sent as Last-Event-ID on reconnection
+	Event string // event type, "message" when the server sends none
+	Data  string
+	Retry time.Duration // reconnection time sent with the event, if any
+}
+
+// eventParser parses a text/event-stream as defined by the HTML standard.
+type eventParser struct {
+	lastID string
+	retry  time.Duration
+
+	typ  string
+	data bytes.Buffer
+	has  bool // data 字段出现过
+}
+
+// parse reads r and calls fn for every complete event.
+func (p *eventParser) parse(r io.Reader, fn func(Event) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 4096), 1<<20)
+	s.Split(scanEventLines)
+
+	first := true
+	for s.Scan() {
+		line := s.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+
+		if line == "" {
+			if err := p.dispatch(fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			p.typ = value
+		case "data":
+			p.data.WriteString(value)
+			p.data.WriteByte('\n')
+			p.has = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				p.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				p.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	// 不完整的事件被丢弃
+	p.typ, p.has = "", false
+	p.data.Reset()
+
+	return s.Err()
+}
+
+func (p *eventParser) dispatch(fn func(Event) error) error {
+	defer func() {
+		p.typ, p.has = "", false
+		p.data.Reset()
+	}()
+
+	if !p.has {
+		return nil
+	}
+
+	e := Event{
+		ID:    p.lastID,
+		Event: p.typ,
+		Data:  strings.TrimSuffix(p.data.String(), "\n"),
+		Retry: p.retry,
+	}
+
+	if e.Event == "" {
+		e.Event = "message"
+	}
+
+	return fn(e)
+}
+
+// scanEventLines splits lines ending with CRLF, LF or CR.
+func scanEventLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+
+		// 需要知道 CR 后面是否跟着 LF
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+
+		return 0, nil, nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// ReadEvents parses the response body as a text/event-stream and calls fn
+// for every event until the body ends, fn returns an error or the request
+// is canceled. Returning ErrStopEvents from fn stops reading without error.
+// The body is closed when ReadEvents returns.
+func (r *Response) ReadEvents(fn func(Event) error) error {
+	return r.readEvents(new(eventParser), fn)
+}
+
+func (r *Response) readEvents(p *eventParser, fn func(Event) error) error {
+	defer r.Body.Close()
+
+	err := p.parse(r.Body, fn)
+	if err == ErrStopEvents {
+		return nil
+	}
+	return err
+}
+
+// EventSource consumes server-sent events and reconnects when the
+// connection is lost, sending the last event ID in the Last-Event-ID header
+// and waiting for the reconnection time sent by the server:
+//
+//	es := httpclient.NewEventSource(func() *httpclient.Client {
+//		return session.To("GET", "/events")
+//	})
+//	err := es.Listen(ctx, func(e httpclient.Event) error {
+//		fmt.Println(e.Event, e.Data)
+//		return nil
+//	})
+//
+// The request returned by newRequest must not have a timeout, which would
+// end every connection. EventSource stops when the server answers 204 No
+// Content, and fails when it answers another status than 200 or another
+// content type than text/event-stream.
+type EventSource struct {
+	newRequest func() *Client
+	retry      time.Duration
+	maxRetries int
+
+	mu     sync.Mutex
+	lastID string
+	err    error
+}
+
+// NewEventSource returns an EventSource opening its connections with the
+// requests returned by newRequest.
+func NewEventSource(newRequest func() *Client) *EventSource {
+	return &EventSource{
+		newRequest: newRequest,
+		retry:      3 * time.Second,
+		maxRetries: -1,
+	}
+}
+
+// SetRetry sets the reconnection time used until the server sends one, it
+// defaults to 3 seconds.
+func (s *EventSource) SetRetry(d time.Duration) *EventSource {
+	s.retry = d
+	return s
+}
+
+// SetMaxRetries sets how many consecutive failed connections are retried,
+// -1, the default, means forever.
+func (s *EventSource) SetMaxRetries(n int) *EventSource {
+	s.maxRetries = n
+	return s
+}
+
+// SetLastEventID sets the Last-Event-ID sent with the first connection, to
+// resume a stream.
+func (s *EventSource) SetLastEventID(id string) *EventSource {
+	s.mu.Lock()
+	s.lastID = id
+	s.mu.Unlock()
+	return s
+}
+
+// LastEventID returns the ID of the last event received.
+func (s *EventSource) LastEventID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastID
+}
+
+func (s *EventSource) setLastEventID(id string) {
+	s.mu.Lock()
+	s.lastID = id
+	s.mu.Unlock()
+}
+
+// Listen connects and calls fn for every event until ctx is done, fn
+// returns an error or the stream can not be resumed. It returns nil when
+// ctx is done, the server ends the stream with 204 No Content or fn returns
+// ErrStopEvents.
+func (s *EventSource) Listen(ctx context.Context, fn func(Event) error) error {
+	p := &eventParser{lastID: s.LastEventID()}
+	failures := 0
+
+	for {
+		received := false
+		err := s.connect(ctx, p, func(e Event) error {
+			received = true
+			s.setLastEventID(e.ID)
+			return fn(e)
+		})
+
+		s.setLastEventID(p.lastID)
+
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err == ErrStopEvents || err == errStreamEnded:
+			return nil
+		}
+
+		if se, ok := err.(streamError); ok {
+			return se.error
+		}
+
+		if received {
+			failures = 0
+		} else if failures++; s.maxRetries >= 0 && failures > s.maxRetries {
+			if err == nil {
+				err = errors.New("request: event stream closed")
+			}
+			return err
+		}
+
+		delay := s.retry
+		if p.retry > 0 {
+			delay = p.retry
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Events is like Listen but delivers the events over a channel, which is
+// closed when listening stops. Err returns the error which stopped it.
+func (s *EventSource) Events(ctx context.Context) <-chan Event {
+	ch := make(chan Event)
+
+	go func() {
+		defer close(ch)
+
+		err := s.Listen(ctx, func(e Event) error {
+			select {
+			case ch <- e:
+				return nil
+			case <-ctx.Done():
+				return ErrStopEvents
+			}
+		})
+
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+	}()
+
+	return ch
+}
+
+// Err returns the error which stopped the channel returned by Events.
+func (s *EventSource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+var errStreamEnded = errors.New("request: event stream ended by the server")
+
+// streamError is an error which ends the stream without reconnection.
+type streamError struct {
+	error
+}
+
+// connect opens a connection and reads its events. A nil error means the
+// server closed the connection.
+func (s *EventSource) connect(ctx context.Context, p *eventParser, fn func(Event) error) error {
+	c := s.newRequest().SetContext(ctx).
+		SetHeader("Accept", "text/event-stream").
+		SetHeader("Cache-Control", "no-cache")
+
+	if p.lastID != "" {
+		c.SetHeader("Last-Event-ID", p.lastID)
+	}
+
+	res, err := c.Execute()
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusNoContent {
+		res.Body.Close()
+		return errStreamEnded
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return streamError{fmt.Errorf("request: event stream answered %s", res.Status)}
+	}
+
+	if mt, _, _ := mime.ParseMediaType(res.ContentType()); mt != "text/event-stream" {
+		res.Body.Close()
+		return streamError{fmt.Errorf("request: event stream has content type %q", res.ContentType())}
+	}
+
+	err = res.readEvents(p, func(e Event) error {
+		if err := fn(e); err != nil {
+			return streamError{err}
+		}
+		return nil
+	})
+
+	if se, ok := err.(streamError); ok && se.error == ErrStopEvents {
+		return ErrStopEvents
+	}
+
+	return err
+}
@@ -0,0 +1,234 @@
+package httpclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sync"
+)
+
+// Stream decodes the JSON values of a response body one at a time, so
+// large bodies are processed with bounded memory. The body may be
+// newline-delimited JSON (NDJSON), any sequence of whitespace separated
+// JSON values, or a top-level JSON array whose elements are decoded one by
+// one. A body starting with "[" is taken as an array unless its
+// Content-Type is an NDJSON one such as "application/x-ndjson", whose
+// records may be arrays themselves:
+//
+//	s := httpclient.NewStream[Item](res)
+//	defer s.Close()
+//	for s.Next() {
+//		item := s.Value()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type Stream[T any] struct {
+	res   *Response
+	dec   *json.Decoder
+	array bool
+	value T
+	err   error
+	done  bool
+}
+
+// NewStream returns a Stream decoding the body of r into values of type T.
+// A non-2xx response is reported by Err as ErrStatusNotOk.
+func NewStream[T any](r *Response) *Stream[T] {
+	s := &Stream[T]{res: r}
+
+	if !r.OK() {
+		s.fail(ErrStatusNotOk{statusCode: r.StatusCode})
+		return s
+	}
+
+	br := bufio.NewReader(r.Body)
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			s.fail(nil)
+			return s
+		}
+
+		if err != nil {
+			s.fail(err)
+			return s
+		}
+
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+
+		br.UnreadByte()
+		s.array = b == '[' && !ndjson(r)
+		break
+	}
+
+	s.dec = json.NewDecoder(br)
+
+	if s.array {
+		if _, err := s.dec.Token(); err != nil {
+			s.fail(err)
+		}
+	}
+
+	return s
+}
+
+// Next decodes the next value, it returns false at the end of the body or
+// on error.
+func (s *Stream[T]) Next() bool {
+	if s.done {
+		return false
+	}
+
+	if s.array && !s.dec.More() {
+		if t, err := s.dec.Token(); err != nil || t != json.Delim(']') {
+			if err == nil {
+				err = fmt.Errorf("request: unexpected %v at the end of the JSON array", t)
+			}
+			s.fail(err)
+			return false
+		}
+
+		// 数组后还有数据时多半是元素为数组的 NDJSON
+		if _, err := s.dec.Token(); err != io.EOF {
+			if err == nil {
+				err = errors.New("request: unexpected data after the JSON array, the body may be NDJSON without an NDJSON Content-Type")
+			}
+			s.fail(err)
+			return false
+		}
+
+		s.fail(nil)
+		return false
+	}
+
+	var v T
+	if err := s.dec.Decode(&v); err != nil {
+		if err == io.EOF && !s.array {
+			err = nil
+		}
+		s.fail(err)
+		return false
+	}
+
+	s.value = v
+	return true
+}
+
+// ndjsonTypes are the media types of the bodies made of a sequence of JSON
+// values.
+var ndjsonTypes = map[string]bool{
+	"application/x-ndjson":      true,
+	"application/ndjson":        true,
+	"application/jsonl":         true,
+	"application/x-jsonlines":   true,
+	"application/stream+json":   true,
+	"application/x-json-stream": true,
+}
+
+func ndjson(r *Response) bool {
+	mt, _, _ := mime.ParseMediaType(r.ContentType())
+	return ndjsonTypes[mt]
+}
+
+// Value returns the value decoded by the last call to Next.
+func (s *Stream[T]) Value() T {
+	return s.value
+}
+
+// Err returns the error which stopped the stream, if any.
+func (s *Stream[T]) Err() error {
+	return s.err
+}
+
+// Close closes the response body, it is closed automatically once the
+// stream ends.
+func (s *Stream[T]) Close() error {
+	s.done = true
+	return s.res.Body.Close()
+}
+
+func (s *Stream[T]) fail(err error) {
+	s.err, s.done = err, true
+	s.res.Body.Close()
+}
+
+// Each decodes the values of the body of r like Stream and calls fn for
+// every one of them, until the body ends or fn returns an error.
+func Each[T any](r *Response, fn func(T) error) error {
+	s := NewStream[T](r)
+	defer s.Close()
+
+	for s.Next() {
+		if err := fn(s.Value()); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+// NDJSONWriter writes values as newline-delimited JSON.
+type NDJSONWriter struct {
+	enc *json.Encoder
+}
+
+// Write encodes v as one line of JSON.
+func (w *NDJSONWriter) Write(v interface{}) error {
+	return w.enc.Encode(v)
+}
+
+// SendNDJSON streams the values written by fn as the request body, with
+// the "Content-Type" header set to "application/x-ndjson". fn runs in its
+// own goroutine while the request is sent, so the body is never held in
+// memory; such a request can not be retried.
+func (c *Client) SendNDJSON(fn func(w *NDJSONWriter) error) *Client {
+	if c.body != nil || len(c.files) != 0 {
+		c.err = ErrBodyAlreadySet
+		return c
+	}
+
+	c.body = &ndjsonBody{fn: fn}
+	return c.SetContentType("application/x-ndjson")
+}
+
+// ndjsonBody runs the producer on the first Read.
+type ndjsonBody struct {
+	fn   func(w *NDJSONWriter) error
+	once sync.Once
+	pr   *io.PipeReader
+}
+
+func (b *ndjsonBody) start() {
+	b.once.Do(func() {
+		pr, pw := io.Pipe()
+		b.pr = pr
+
+		go func() {
+			pw.CloseWithError(b.fn(&NDJSONWriter{enc: json.NewEncoder(pw)}))
+		}()
+	})
+}
+
+func (b *ndjsonBody) Read(p []byte) (int, error) {
+	b.start()
+	if b.pr == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return b.pr.Read(p)
+}
+
+func (b *ndjsonBody) Close() error {
+	b.once.Do(func() {})
+	if b.pr != nil {
+		return b.pr.Close()
+	}
+	return nil
+}
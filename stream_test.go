@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        []string
+		err         bool
+	}{
+		{"application/x-ndjson", `{"a":1}` + "\n" + `{"a":2}` + "\n", []string{`{"a":1}`, `{"a":2}`}, false},
+		{"application/x-ndjson", "[1,2]\n[3,4]\n", []string{"[1,2]", "[3,4]"}, false},
+		{"application/x-ndjson; charset=utf-8", "[1]\n[2]", []string{"[1]", "[2]"}, false},
+		{"application/jsonl", "[1]\n", []string{"[1]"}, false},
+		{"application/json", `[{"a":1}, {"a":2}]`, []string{`{"a":1}`, `{"a":2}`}, false},
+		{"application/json", " \n[1, [2, 3]] \n", []string{"1", "[2,3]"}, false},
+		{"application/json", "[]", nil, false},
+		{"", `{"a":1} {"a":2}`, []string{`{"a":1}`, `{"a":2}`}, false},
+		{"", "", nil, false},
+
+		// 数组后还有数据
+		{"application/json", "[1,2]\n[3,4]\n", []string{"1", "2"}, true},
+		{"", "[1]\n2", []string{"1"}, true},
+		{"application/json", "[1,2", []string{"1", "2"}, true},
+		{"application/x-ndjson", "{\"a\":1}\n{", []string{`{"a":1}`}, true},
+	}
+
+	for _, tt := range tests {
+		res := &Response{Response: &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {tt.contentType}},
+			Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+		}}
+
+		var got []string
+		s := NewStream[json.RawMessage](res)
+		for s.Next() {
+			b, _ := json.Marshal(s.Value())
+			got = append(got, string(b))
+		}
+
+		if !reflect.DeepEqual(got, tt.want) || (s.Err() != nil) != tt.err {
+			t.Errorf("%q %q: values %q, err %v, want %q, error %v", tt.contentType, tt.body, got, s.Err(), tt.want, tt.err)
+		}
+	}
+}
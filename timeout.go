@@ -0,0 +1,288 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Timeouts limits the phases of a request. A zero duration inherits the
+// value of the session, then of Settings(), a negative one disables the
+// limit.
+type Timeouts struct {
+	// Connect limits getting a connection: resolving the host, dialing it
+	// and, through a proxy, opening the tunnel.
+	Connect time.Duration
+	// TLSHandshake limits the TLS handshake.
+	TLSHandshake time.Duration
+	// ResponseHeader limits the wait for the response once the request is
+	// written.
+	ResponseHeader time.Duration
+	// IdleRead limits how long a read of the response body waits for data,
+	// so a stalled body fails while a slow large download goes on.
+	IdleRead time.Duration
+	// Total limits the whole request, retries, redirects and reading the
+	// response body included.
+	Total time.Duration
+}
+
+// merge fills the zero durations of t with the ones of d.
+func (t Timeouts) merge(d Timeouts) Timeouts {
+	fill := func(v *time.Duration, dv time.Duration) {
+		if *v == 0 {
+			*v = dv
+		}
+	}
+
+	fill(&t.Connect, d.Connect)
+	fill(&t.TLSHandshake, d.TLSHandshake)
+	fill(&t.ResponseHeader, d.ResponseHeader)
+	fill(&t.IdleRead, d.IdleRead)
+	fill(&t.Total, d.Total)
+	return t
+}
+
+func (t Timeouts) enabled() bool {
+	return t.Connect > 0 || t.TLSHandshake > 0 || t.ResponseHeader > 0 || t.IdleRead > 0 || t.Total > 0
+}
+
+// TimeoutPhase identifies the phase of a request limited by a timeout.
+type TimeoutPhase int
+
+// Timeout phases.
+const (
+	TimeoutConnect TimeoutPhase = iota + 1
+	TimeoutTLSHandshake
+	TimeoutResponseHeader
+	TimeoutIdleRead
+	TimeoutTotal
+)
+
+func (p TimeoutPhase) String() string {
+	switch p {
+	case TimeoutConnect:
+		return "connect"
+	case TimeoutTLSHandshake:
+		return "TLS handshake"
+	case TimeoutResponseHeader:
+		return "response header"
+	case TimeoutIdleRead:
+		return "idle read"
+	case TimeoutTotal:
+		return "total"
+	}
+	return fmt.Sprintf("TimeoutPhase(%d)", int(p))
+}
+
+// TimeoutError is the error of a request which exceeded one of its
+// Timeouts. It is returned by Execute wrapped in a *url.Error, or by the
+// reads of the response body, and matches context.DeadlineExceeded:
+//
+//	var te *httpclient.TimeoutError
+//	if errors.As(err, &te) && te.Phase == httpclient.TimeoutConnect {
+//		...
+//	}
+type TimeoutError struct {
+	Phase    TimeoutPhase
+	Duration time.Duration // the timeout which expired
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("request: %s timeout of %v exceeded", e.Phase, e.Duration)
+}
+
+// Timeout reports true, as net.Error.
+func (e *TimeoutError) Timeout() bool { return true }
+
+// Temporary reports true, as net.Error.
+func (e *TimeoutError) Temporary() bool { return true }
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *TimeoutError) Unwrap() error { return context.DeadlineExceeded }
+
+// SetTimeouts sets the default timeouts of the requests.
+func (c *ClientSetting) SetTimeouts(t Timeouts) *ClientSetting {
+	c.timeouts = t
+	return c
+}
+
+// SetTimeouts sets the timeouts of the requests of the session, the zero
+// durations inherit the ones set by Settings().SetTimeouts.
+func (s *Session) SetTimeouts(t Timeouts) *Session {
+	s.timeouts = t
+	return s
+}
+
+// SetTimeouts sets the timeouts of the request, the zero durations inherit
+// the ones of the session and of Settings(). SetTimeout sets the total
+// timeout only.
+func (c *Client) SetTimeouts(t Timeouts) *Client {
+	c.timeouts = t.merge(Timeouts{Total: c.timeouts.Total})
+	return c
+}
+
+// resolvedTimeouts returns the timeouts of the request, Settings().Timeout
+// being the default total timeout.
+func (c *Client) resolvedTimeouts() Timeouts {
+	t := c.timeouts
+	if c.session != nil {
+		t = t.merge(c.session.timeouts)
+	}
+
+	t = t.merge(Settings().timeouts)
+	return t.merge(Timeouts{Total: Settings().Timeout})
+}
+
+// timeoutGuard enforces the timeouts of a request. Each attempt gets a
+// context of its own, canceled with a *TimeoutError when a phase lasts too
+// long, inside the context canceled when the total timeout expires.
+type timeoutGuard struct {
+	timeouts Timeouts
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	total    *time.Timer
+
+	mu      sync.Mutex
+	attempt context.Context
+	abort   context.CancelCauseFunc
+	phase   *time.Timer
+}
+
+func newTimeoutGuard(parent context.Context, t Timeouts) *timeoutGuard {
+	g := &timeoutGuard{timeouts: t}
+	g.ctx, g.cancel = context.WithCancelCause(parent)
+
+	if t.Total > 0 {
+		g.total = time.AfterFunc(t.Total, func() {
+			g.cancel(&TimeoutError{Phase: TimeoutTotal, Duration: t.Total})
+		})
+	}
+
+	return g
+}
+
+// newAttempt returns the context of the next attempt, the previous one is
+// canceled.
+func (g *timeoutGuard) newAttempt() context.Context {
+	g.mu.Lock()
+	if g.abort != nil {
+		g.stopPhaseLocked()
+		g.abort(nil)
+	}
+	g.attempt, g.abort = context.WithCancelCause(g.ctx)
+	ctx := g.attempt
+	g.mu.Unlock()
+
+	t := g.timeouts
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn:              func(string) { g.startPhase(TimeoutConnect, t.Connect) },
+		TLSHandshakeStart:    func() { g.startPhase(TimeoutTLSHandshake, t.TLSHandshake) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { g.stopPhase() },
+		GotConn:              func(httptrace.GotConnInfo) { g.stopPhase() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { g.startPhase(TimeoutResponseHeader, t.ResponseHeader) },
+		GotFirstResponseByte: func() { g.stopPhase() },
+	})
+}
+
+// startPhase arms the timer of a phase, replacing the one of the previous
+// phase.
+func (g *timeoutGuard) startPhase(phase TimeoutPhase, d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.stopPhaseLocked()
+	if d <= 0 || g.abort == nil {
+		return
+	}
+
+	abort := g.abort
+	g.phase = time.AfterFunc(d, func() {
+		abort(&TimeoutError{Phase: phase, Duration: d})
+	})
+}
+
+func (g *timeoutGuard) stopPhase() {
+	g.mu.Lock()
+	g.stopPhaseLocked()
+	g.mu.Unlock()
+}
+
+func (g *timeoutGuard) stopPhaseLocked() {
+	if g.phase != nil {
+		g.phase.Stop()
+		g.phase = nil
+	}
+}
+
+// finish stops the timers and releases the contexts.
+func (g *timeoutGuard) finish() {
+	g.mu.Lock()
+	g.stopPhaseLocked()
+	g.mu.Unlock()
+
+	if g.total != nil {
+		g.total.Stop()
+	}
+	g.cancel(nil)
+}
+
+// wrap replaces the error caused by the cancellation of the request by the
+// *TimeoutError which canceled it.
+func (g *timeoutGuard) wrap(err error) error {
+	g.mu.Lock()
+	ctx := g.attempt
+	g.mu.Unlock()
+
+	if ctx == nil {
+		ctx = g.ctx
+	}
+
+	var te *TimeoutError
+	if !errors.As(context.Cause(ctx), &te) {
+		return err
+	}
+
+	if ue, ok := err.(*url.Error); ok {
+		return &url.Error{Op: ue.Op, URL: ue.URL, Err: te}
+	}
+	return te
+}
+
+// timeoutBody limits the reads of a response body with the idle read
+// timeout and stops the timers once the body is read or closed.
+type timeoutBody struct {
+	io.ReadCloser
+	guard *timeoutGuard
+	once  sync.Once
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	b.guard.startPhase(TimeoutIdleRead, b.guard.timeouts.IdleRead)
+	n, err := b.ReadCloser.Read(p)
+	b.guard.stopPhase()
+
+	switch {
+	case err == io.EOF:
+		b.done()
+	case err != nil:
+		err = b.guard.wrap(err)
+	}
+
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+func (b *timeoutBody) done() {
+	b.once.Do(b.guard.finish)
+}
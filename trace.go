@@ -0,0 +1,171 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// clientTrace collects the timestamps of the phases of a request through
+// net/http/httptrace. When the request is redirected the phases describe
+// the last hop.
+type clientTrace struct {
+	mu sync.Mutex
+
+	start        time.Time
+	getConn      time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	bodyDone     time.Time
+
+	reused     bool
+	wasIdle    bool
+	idleTime   time.Duration
+	remoteAddr string
+
+	conn *trackedConn // 连接池统计中占用的连接
+	hop  bool         // 单跳的 trace 不计入连接池统计
+}
+
+func newClientTrace() *clientTrace {
+	return &clientTrace{start: time.Now()}
+}
+
+func (t *clientTrace) set(field *time.Time) {
+	t.mu.Lock()
+	*field = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *clientTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			t.getConn = time.Now()
+			t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+			t.connectStart, t.connectDone = time.Time{}, time.Time{}
+			t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { t.set(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone:       func(string, string, error) { t.set(&t.connectDone) },
+		TLSHandshakeStart: func() { t.set(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.set(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.gotConn = time.Now()
+			t.reused, t.wasIdle, t.idleTime = info.Reused, info.WasIdle, info.IdleTime
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+			// 重试或重定向时先释放上一个连接
+			if !t.hop {
+				if t.conn != nil {
+					t.conn.release()
+				}
+				if t.conn = trackedConnOf(info.Conn); t.conn != nil {
+					t.conn.acquire()
+				}
+			}
+			t.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte) },
+	}
+}
+
+// release marks the connection of the request as no longer in use.
+func (t *clientTrace) release() {
+	t.mu.Lock()
+	if t.conn != nil {
+		t.conn.release()
+		t.conn = nil
+	}
+	t.mu.Unlock()
+}
+
+// snapshot returns a copy of the trace which is safe to read.
+func (t *clientTrace) snapshot() clientTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return clientTrace{
+		start:        t.start,
+		getConn:      t.getConn,
+		dnsStart:     t.dnsStart,
+		dnsDone:      t.dnsDone,
+		connectStart: t.connectStart,
+		connectDone:  t.connectDone,
+		tlsStart:     t.tlsStart,
+		tlsDone:      t.tlsDone,
+		gotConn:      t.gotConn,
+		wroteRequest: t.wroteRequest,
+		firstByte:    t.firstByte,
+		bodyDone:     t.bodyDone,
+		reused:       t.reused,
+		wasIdle:      t.wasIdle,
+		idleTime:     t.idleTime,
+		remoteAddr:   t.remoteAddr,
+	}
+}
+
+// traceBody marks the end of the content transfer when the response body
+// is read to the end or closed, copies what is read to tee if set and then
+// calls onDone with the number of bytes read and whether the end was
+// reached.
+type traceBody struct {
+	io.ReadCloser
+	trace  *clientTrace
+	tee    io.Writer
+	onDone func(n int64, eof bool)
+	n      int64
+	eof    bool
+	once   sync.Once
+}
+
+func (b *traceBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+
+	if n > 0 && b.tee != nil {
+		b.tee.Write(p[:n])
+	}
+
+	if err == io.EOF {
+		b.eof = true
+		b.done()
+	}
+
+	return n, err
+}
+
+func (b *traceBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *traceBody) done() {
+	b.once.Do(func() {
+		b.trace.set(&b.trace.bodyDone)
+		if b.onDone != nil {
+			b.onDone(b.n, b.eof)
+		}
+	})
+}
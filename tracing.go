@@ -0,0 +1,169 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+)
+
+// Tracer starts the client spans of the requests. It is a small subset of
+// the OpenTelemetry tracing API, so an adapter over an OpenTelemetry
+// trace.Tracer only takes a few lines.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, if any.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is the client span of a request.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttributes(attrs ...Attribute)
+	AddEvent(name string, attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key, value pair describing a span or an event.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Sampled    bool
+	TraceState string // W3C tracestate，原样传递
+}
+
+// IsValid reports whether the trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Propagator injects a span context into the headers of a request.
+type Propagator interface {
+	Inject(sc SpanContext, h http.Header)
+}
+
+// TraceContext is the W3C Trace Context propagator writing the traceparent
+// and tracestate headers.
+type TraceContext struct{}
+
+// Inject implements Propagator.
+func (TraceContext) Inject(sc SpanContext, h http.Header) {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	h.Set("traceparent", "00-"+hex.EncodeToString(sc.TraceID[:])+"-"+hex.EncodeToString(sc.SpanID[:])+"-"+flags)
+
+	if sc.TraceState != "" {
+		h.Set("tracestate", sc.TraceState)
+	}
+}
+
+// B3 is the Zipkin B3 propagator, writing the X-B3-* headers or the single
+// b3 header.
+type B3 struct {
+	SingleHeader bool
+}
+
+// Inject implements Propagator.
+func (b B3) Inject(sc SpanContext, h http.Header) {
+	traceID, spanID := hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:])
+
+	sampled := "0"
+	if sc.Sampled {
+		sampled = "1"
+	}
+
+	if b.SingleHeader {
+		h.Set("b3", traceID+"-"+spanID+"-"+sampled)
+		return
+	}
+
+	h.Set("X-B3-TraceId", traceID)
+	h.Set("X-B3-SpanId", spanID)
+	h.Set("X-B3-Sampled", sampled)
+}
+
+// DefaultPropagators are used when SetTracer is given none.
+var DefaultPropagators = []Propagator{TraceContext{}, B3{}}
+
+type tracing struct {
+	tracer      Tracer
+	propagators []Propagator
+}
+
+func newTracing(t Tracer, propagators []Propagator) *tracing {
+	if t == nil {
+		return nil
+	}
+
+	if len(propagators) == 0 {
+		propagators = DefaultPropagators
+	}
+
+	return &tracing{tracer: t, propagators: propagators}
+}
+
+// SetTracer sets the Tracer creating a span for every request which is not
+// sent from a session with its own Tracer, and the propagators injecting
+// the span into the request headers, DefaultPropagators if none are given.
+func (c *ClientSetting) SetTracer(t Tracer, propagators ...Propagator) *ClientSetting {
+	c.tracing = newTracing(t, propagators)
+	return c
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SpanContext() SpanContext      { return SpanContext{} }
+func (nopSpan) SetAttributes(...Attribute)    {}
+func (nopSpan) AddEvent(string, ...Attribute) {}
+func (nopSpan) RecordError(error)             {}
+func (nopSpan) End()                          {}
+
+// startSpan starts the span of the request and injects it into the request
+// headers. The span is named after the method, and the URL template when
+// the URL was given as one.
+func (c *Client) startSpan() Span {
+	t := Settings().tracing
+	if c.session != nil && c.session.tracing != nil {
+		t = c.session.tracing
+	}
+
+	if t == nil {
+		return nopSpan{}
+	}
+
+	name := c.req.Method
+	if c.template != "" {
+		name += " " + c.template
+	}
+
+	ctx, span := t.tracer.Start(c.req.Context(), name)
+	c.req = c.req.WithContext(ctx)
+
+	attrs := []Attribute{
+		{"http.request.method", c.req.Method},
+		{"url.full", c.req.URL.String()},
+		{"server.address", c.req.URL.Hostname()},
+	}
+
+	if c.template != "" {
+		attrs = append(attrs, Attribute{"url.template", c.template})
+	}
+
+	span.SetAttributes(attrs...)
+
+	if sc := span.SpanContext(); sc.IsValid() {
+		for _, p := range t.propagators {
+			p.Inject(sc, c.req.Header)
+		}
+	}
+
+	return span
+}
@@ -0,0 +1,235 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Dialer opens the network connections of the requests. *net.Dialer
+// implements it, as may a custom network stack such as an in-memory
+// network in tests or a userspace VPN.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// DialerFunc adapts an ordinary function to a Dialer.
+type DialerFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// DialContext calls f(ctx, network, addr).
+func (f DialerFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+// proxyDialer adapts a Dialer to the dialer used by the SOCKS5 proxies.
+type proxyDialer struct {
+	Dialer
+}
+
+func (d proxyDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// socksDialer returns the dialer of the SOCKS5 proxy u which is reached
+// through forward. socks5h is accepted as socks5, the proxy resolves the
+// host names in both cases.
+func socksDialer(u *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+	if u.Scheme == "socks5h" {
+		c := *u
+		c.Scheme = "socks5"
+		u = &c
+	}
+
+	return proxy.FromURL(u, forward)
+}
+
+// SetDialer sets the Dialer opening the connections of the requests, also
+// used to reach the proxy set by SetProxy.
+func (c *ClientSetting) SetDialer(d Dialer) *ClientSetting {
+	c.dialer = d
+
+	if c.proxyType == CustomProxy && c.ProxyTransport != nil && c.ProxyTransport.Dial != nil {
+		// SOCKS5 代理的 Dial 需要重新创建
+		return c.SetProxy(c.proxyType, c.proxyURL)
+	}
+
+	c.reinstall()
+	return c
+}
+
+// dialContext returns the dial function of the transports created by
+// SetProxy.
+func (c *ClientSetting) dialContext(d *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.dialer != nil {
+		return c.dialer.DialContext
+	}
+	return d.DialContext
+}
+
+// SetDialer sets the Dialer opening the connections of the requests of the
+// session instead of the one set by Settings().SetDialer.
+func (s *Session) SetDialer(d Dialer) *Session {
+	s.transports.update(func(t *sessionTransport) { t.dialer = d })
+	return s
+}
+
+// SetUnixSocket sends the requests of the session to the Unix domain socket
+// at path, whatever the host of their URL, like curl --unix-socket. path
+// may be given as a "unix://" URL, and a name starting with "@" is a socket
+// of the Linux abstract namespace. When no base URL was set, it becomes
+// "http://localhost" so the requests may use paths only:
+//
+//	docker := httpclient.NewSession().SetUnixSocket("/var/run/docker.sock")
+//	res, err := docker.To("GET", "/v1.41/containers/json").Execute()
+func (s *Session) SetUnixSocket(path string) *Session {
+	path = strings.TrimPrefix(path, "unix://")
+
+	if s.baseURL == nil {
+		s.baseURL = &url.URL{Scheme: "http", Host: "localhost"}
+	}
+
+	s.transports.update(func(t *sessionTransport) { t.socket = path })
+	return s
+}
+
+// sessionTransport holds the options of a session applied to its transport
+// and the transport derived from them.
+type sessionTransport struct {
+	mu       sync.Mutex
+	protocol Protocol
+	dialer   Dialer
+	socket   string
+	pool     *PoolOptions
+
+	hosts         map[string][]net.IPAddr
+	resolver      Resolver
+	preference    IPPreference
+	fallbackDelay time.Duration
+	preferenceSet bool
+
+	base    *http.Transport
+	derived *http.Transport
+}
+
+func (t *sessionTransport) update(fn func(t *sessionTransport)) {
+	t.mu.Lock()
+	fn(t)
+	t.discard()
+	t.mu.Unlock()
+}
+
+// discard drops the derived transport, closing its idle connections.
+func (t *sessionTransport) discard() {
+	if t.derived != nil {
+		t.derived.CloseIdleConnections()
+		trackers.Delete(t.derived)
+		t.derived = nil
+	}
+}
+
+// customized reports whether the session changes its transport.
+func (t *sessionTransport) customized() bool {
+	return t.protocol != 0 || t.dialer != nil || t.socket != "" || t.pool != nil || t.resolves()
+}
+
+// resolves reports whether the session resolves the host names itself.
+func (t *sessionTransport) resolves() bool {
+	return t.hosts != nil || t.resolver != nil || t.preferenceSet
+}
+
+// derive returns base configured with the options of the session. The
+// derived transport is kept as long as base does not change, so its
+// connections are reused.
+func (t *sessionTransport) derive(base *http.Transport) *http.Transport {
+	if t.base == base && t.derived != nil {
+		return t.derived
+	}
+	t.discard()
+
+	d := base.Clone()
+	untrack(d, base)
+
+	if t.protocol != 0 {
+		t.protocol.apply(d)
+	}
+
+	dialer := t.dialer
+	if dialer == nil && t.pool != nil && Settings().dialer == nil && d.Dial == nil {
+		dialer = t.pool.dialer()
+	}
+
+	if t.pool != nil {
+		t.pool.apply(d)
+	}
+
+	if dialer != nil {
+		d.DialContext, d.Dial = dialer.DialContext, nil
+	}
+
+	// SOCKS5 代理自己解析域名
+	if t.resolves() && d.Dial == nil {
+		if dialer == nil {
+			dialer = DialerFunc(d.DialContext)
+			if d.DialContext == nil {
+				dialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+			}
+		}
+
+		d.DialContext = (&resolvingDialer{
+			dialer:        dialer,
+			hosts:         t.hosts,
+			resolver:      t.resolver,
+			preference:    t.preference,
+			fallbackDelay: t.fallbackDelay,
+		}).DialContext
+	}
+
+	if t.socket != "" {
+		if dialer == nil {
+			dialer = &net.Dialer{}
+		}
+
+		socket := t.socket
+		d.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		d.Dial, d.Proxy = nil, nil
+	}
+
+	trackTransport(d)
+
+	t.base, t.derived = base, d
+	return d
+}
+
+// roundTripper returns the transport of the requests of the session.
+func (s *Session) roundTripper() http.RoundTripper {
+	rt := s.transport
+	if rt == nil && Settings().ProxyTransport != nil {
+		rt = Settings().ProxyTransport
+	}
+
+	s.transports.mu.Lock()
+	defer s.transports.mu.Unlock()
+
+	if !s.transports.customized() {
+		return rt
+	}
+
+	base, ok := rt.(*http.Transport)
+	if rt == nil {
+		base, ok = http.DefaultTransport.(*http.Transport)
+	}
+
+	if !ok {
+		return rt
+	}
+
+	return s.transports.derive(base)
+}
@@ -0,0 +1,250 @@
+package httpclient
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// templateOperator describes how an RFC 6570 expression operator renders
+// its variables.
+type templateOperator struct {
+	first    string
+	sep      string
+	named    bool
+	ifEmpty  string
+	reserved bool
+}
+
+var templateOperators = map[byte]templateOperator{
+	'+': {first: "", sep: ",", reserved: true},
+	'#': {first: "#", sep: ",", reserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+}
+
+// expandURITemplate expands tmpl as an RFC 6570 (level 4) URI template.
+//
+// Variable values may be a string, a []string or a map[string]string, any
+// other value is formatted with fmt.Sprint. Missing variables, empty lists
+// and empty maps are treated as undefined and produce no output.
+func expandURITemplate(tmpl string, vars map[string]interface{}) (string, error) {
+	var b strings.Builder
+
+	for {
+		i := strings.IndexByte(tmpl, '{')
+		if i < 0 {
+			if strings.IndexByte(tmpl, '}') >= 0 {
+				return "", fmt.Errorf("request: unexpected '}' in URI template")
+			}
+			b.WriteString(tmpl)
+			return b.String(), nil
+		}
+
+		j := strings.IndexByte(tmpl[i:], '}')
+		if j < 0 {
+			return "", fmt.Errorf("request: unclosed expression in URI template")
+		}
+
+		b.WriteString(tmpl[:i])
+
+		if err := expandExpression(&b, tmpl[i+1:i+j], vars); err != nil {
+			return "", err
+		}
+
+		tmpl = tmpl[i+j+1:]
+	}
+}
+
+func expandExpression(b *strings.Builder, expr string, vars map[string]interface{}) error {
+	if expr == "" {
+		return fmt.Errorf("request: empty expression in URI template")
+	}
+
+	op := templateOperator{sep: ","}
+	if o, ok := templateOperators[expr[0]]; ok {
+		op = o
+		expr = expr[1:]
+	}
+
+	first := true
+	for _, spec := range strings.Split(expr, ",") {
+		name, explode, prefix, err := parseVarSpec(spec)
+		if err != nil {
+			return err
+		}
+
+		val, ok := vars[name]
+		if !ok || val == nil {
+			continue
+		}
+
+		var s string
+		switch v := val.(type) {
+		case []string:
+			if len(v) == 0 {
+				continue
+			}
+			s = expandList(name, v, op, explode)
+		case map[string]string:
+			if len(v) == 0 {
+				continue
+			}
+			s = expandMap(name, v, op, explode)
+		default:
+			s = expandString(name, fmt.Sprint(v), op, prefix)
+		}
+
+		if first {
+			b.WriteString(op.first)
+			first = false
+		} else {
+			b.WriteString(op.sep)
+		}
+		b.WriteString(s)
+	}
+
+	return nil
+}
+
+func parseVarSpec(spec string) (name string, explode bool, prefix int, err error) {
+	name = spec
+
+	if strings.HasSuffix(name, "*") {
+		name, explode = name[:len(name)-1], true
+	} else if i := strings.IndexByte(name, ':'); i >= 0 {
+		prefix, err = strconv.Atoi(name[i+1:])
+		if err != nil || prefix <= 0 || prefix >= 10000 {
+			return "", false, 0, fmt.Errorf("request: invalid prefix modifier in URI template: %q", spec)
+		}
+		name = name[:i]
+	}
+
+	if name == "" {
+		return "", false, 0, fmt.Errorf("request: invalid variable in URI template: %q", spec)
+	}
+
+	return name, explode, prefix, nil
+}
+
+func expandString(name, value string, op templateOperator, prefix int) string {
+	if prefix > 0 {
+		if r := []rune(value); len(r) > prefix {
+			value = string(r[:prefix])
+		}
+	}
+
+	if !op.named {
+		return templateEscape(value, op.reserved)
+	}
+
+	if value == "" {
+		return name + op.ifEmpty
+	}
+
+	return name + "=" + templateEscape(value, op.reserved)
+}
+
+func expandList(name string, values []string, op templateOperator, explode bool) string {
+	parts := make([]string, len(values))
+
+	for i, v := range values {
+		parts[i] = templateEscape(v, op.reserved)
+
+		if explode && op.named {
+			if v == "" {
+				parts[i] = name + op.ifEmpty
+			} else {
+				parts[i] = name + "=" + parts[i]
+			}
+		}
+	}
+
+	if explode {
+		return strings.Join(parts, op.sep)
+	}
+
+	if op.named {
+		return name + "=" + strings.Join(parts, ",")
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func expandMap(name string, values map[string]string, op templateOperator, explode bool) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys)*2)
+
+	for _, k := range keys {
+		key, val := templateEscape(k, op.reserved), templateEscape(values[k], op.reserved)
+
+		if !explode {
+			parts = append(parts, key, val)
+		} else if op.named && val == "" {
+			parts = append(parts, key+op.ifEmpty)
+		} else {
+			parts = append(parts, key+"="+val)
+		}
+	}
+
+	if explode {
+		return strings.Join(parts, op.sep)
+	}
+
+	if op.named {
+		return name + "=" + strings.Join(parts, ",")
+	}
+
+	return strings.Join(parts, ",")
+}
+
+const upperhex = "0123456789ABCDEF"
+
+// templateEscape percent-encodes s. Only unreserved characters are kept
+// unless reserved is set, in which case reserved characters and existing
+// pct-encoded triplets are passed through as well.
+func templateEscape(s string, reserved bool) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+
+		switch {
+		case isUnreserved(ch):
+			b.WriteByte(ch)
+		case reserved && isReserved(ch):
+			b.WriteByte(ch)
+		case reserved && ch == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteString(s[i : i+3])
+			i += 2
+		default:
+			b.WriteByte('%')
+			b.WriteByte(upperhex[ch>>4])
+			b.WriteByte(upperhex[ch&15])
+		}
+	}
+
+	return b.String()
+}
+
+func isUnreserved(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' ||
+		ch == '-' || ch == '.' || ch == '_' || ch == '~'
+}
+
+func isReserved(ch byte) bool {
+	return strings.IndexByte(":/?#[]@!$&'()*+,;=", ch) >= 0
+}
+
+func isHex(ch byte) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
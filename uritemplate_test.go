@@ -0,0 +1,238 @@
+package httpclient
+
+import (
+	"testing"
+
+	"github.com/lets-go-go/httpclient/mock"
+)
+
+// templateVars are the variables of the examples of RFC 6570 section 3.2.
+var templateVars = map[string]interface{}{
+	"count":      []string{"one", "two", "three"},
+	"dom":        []string{"example", "com"},
+	"dub":        "me/too",
+	"hello":      "Hello World!",
+	"half":       "50%",
+	"var":        "value",
+	"who":        "fred",
+	"base":       "http://example.com/home/",
+	"path":       "/foo/bar",
+	"list":       []string{"red", "green", "blue"},
+	"keys":       map[string]string{"semi": ";", "dot": ".", "comma": ","},
+	"v":          "6",
+	"x":          "1024",
+	"y":          "768",
+	"empty":      "",
+	"empty_keys": map[string]string{},
+	"undef":      nil,
+	"id":         42,
+	"utf":        "äöü",
+}
+
+// The maps are expanded in key order, unlike the examples of the RFC which
+// keep the order of their definition.
+func TestExpandURITemplate(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		// 3.2.2 简单字符串展开
+		{"{var}", "value"},
+		{"{hello}", "Hello%20World%21"},
+		{"{half}", "50%25"},
+		{"O{empty}X", "OX"},
+		{"O{undef}X", "OX"},
+		{"O{missing}X", "OX"},
+		{"{x,y}", "1024,768"},
+		{"{x,hello,y}", "1024,Hello%20World%21,768"},
+		{"?{x,empty}", "?1024,"},
+		{"?{x,undef}", "?1024"},
+		{"?{undef,y}", "?768"},
+		{"{var:3}", "val"},
+		{"{var:30}", "value"},
+		{"{utf:2}", "%C3%A4%C3%B6"},
+		{"{id}", "42"},
+		{"{list}", "red,green,blue"},
+		{"{list*}", "red,green,blue"},
+		{"{keys}", "comma,%2C,dot,.,semi,%3B"},
+		{"{keys*}", "comma=%2C,dot=.,semi=%3B"},
+
+		// 3.2.3 保留字符展开
+		{"{+var}", "value"},
+		{"{+hello}", "Hello%20World!"},
+		{"{+half}", "50%25"},
+		{"{base}index", "http%3A%2F%2Fexample.com%2Fhome%2Findex"},
+		{"{+base}index", "http://example.com/home/index"},
+		{"O{+empty}X", "OX"},
+		{"O{+undef}X", "OX"},
+		{"{+path}/here", "/foo/bar/here"},
+		{"here?ref={+path}", "here?ref=/foo/bar"},
+		{"up{+path}{var}/here", "up/foo/barvalue/here"},
+		{"{+x,hello,y}", "1024,Hello%20World!,768"},
+		{"{+path,x}/here", "/foo/bar,1024/here"},
+		{"{+path:6}/here", "/foo/b/here"},
+		{"{+list}", "red,green,blue"},
+		{"{+list*}", "red,green,blue"},
+		{"{+keys}", "comma,,,dot,.,semi,;"},
+		{"{+keys*}", "comma=,,dot=.,semi=;"},
+
+		// 3.2.4 片段展开
+		{"{#var}", "#value"},
+		{"{#hello}", "#Hello%20World!"},
+		{"{#half}", "#50%25"},
+		{"foo{#empty}", "foo#"},
+		{"foo{#undef}", "foo"},
+		{"{#x,hello,y}", "#1024,Hello%20World!,768"},
+		{"{#path,x}/here", "#/foo/bar,1024/here"},
+		{"{#path:6}/here", "#/foo/b/here"},
+		{"{#list}", "#red,green,blue"},
+		{"{#list*}", "#red,green,blue"},
+		{"{#keys}", "#comma,,,dot,.,semi,;"},
+		{"{#keys*}", "#comma=,,dot=.,semi=;"},
+
+		// 3.2.5 标签展开
+		{"{.who}", ".fred"},
+		{"{.who,who}", ".fred.fred"},
+		{"{.half,who}", ".50%25.fred"},
+		{"www{.dom*}", "www.example.com"},
+		{"X{.var}", "X.value"},
+		{"X{.empty}", "X."},
+		{"X{.undef}", "X"},
+		{"X{.var:3}", "X.val"},
+		{"X{.list}", "X.red,green,blue"},
+		{"X{.list*}", "X.red.green.blue"},
+		{"X{.keys}", "X.comma,%2C,dot,.,semi,%3B"},
+		{"X{.keys*}", "X.comma=%2C.dot=..semi=%3B"},
+		{"X{.empty_keys}", "X"},
+		{"X{.empty_keys*}", "X"},
+
+		// 3.2.6 路径段展开
+		{"{/who}", "/fred"},
+		{"{/who,who}", "/fred/fred"},
+		{"{/half,who}", "/50%25/fred"},
+		{"{/who,dub}", "/fred/me%2Ftoo"},
+		{"{/var}", "/value"},
+		{"{/var,empty}", "/value/"},
+		{"{/var,undef}", "/value"},
+		{"{/var,x}/here", "/value/1024/here"},
+		{"{/var:1,var}", "/v/value"},
+		{"{/list}", "/red,green,blue"},
+		{"{/list*}", "/red/green/blue"},
+		{"{/list*,path:4}", "/red/green/blue/%2Ffoo"},
+		{"{/keys}", "/comma,%2C,dot,.,semi,%3B"},
+		{"{/keys*}", "/comma=%2C/dot=./semi=%3B"},
+
+		// 3.2.7 路径参数展开
+		{"{;who}", ";who=fred"},
+		{"{;half}", ";half=50%25"},
+		{"{;empty}", ";empty"},
+		{"{;v,empty,who}", ";v=6;empty;who=fred"},
+		{"{;v,bar,who}", ";v=6;who=fred"},
+		{"{;x,y}", ";x=1024;y=768"},
+		{"{;x,y,empty}", ";x=1024;y=768;empty"},
+		{"{;x,y,undef}", ";x=1024;y=768"},
+		{"{;hello:5}", ";hello=Hello"},
+		{"{;list}", ";list=red,green,blue"},
+		{"{;list*}", ";list=red;list=green;list=blue"},
+		{"{;keys}", ";keys=comma,%2C,dot,.,semi,%3B"},
+		{"{;keys*}", ";comma=%2C;dot=.;semi=%3B"},
+
+		// 3.2.8 查询展开
+		{"{?who}", "?who=fred"},
+		{"{?half}", "?half=50%25"},
+		{"{?x,y}", "?x=1024&y=768"},
+		{"{?x,y,empty}", "?x=1024&y=768&empty="},
+		{"{?x,y,undef}", "?x=1024&y=768"},
+		{"{?var:3}", "?var=val"},
+		{"{?list}", "?list=red,green,blue"},
+		{"{?list*}", "?list=red&list=green&list=blue"},
+		{"{?keys}", "?keys=comma,%2C,dot,.,semi,%3B"},
+		{"{?keys*}", "?comma=%2C&dot=.&semi=%3B"},
+		{"{?undef,empty_keys}", ""},
+
+		// 3.2.9 查询续接
+		{"{&who}", "&who=fred"},
+		{"{&half}", "&half=50%25"},
+		{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+		{"{&x,y,empty}", "&x=1024&y=768&empty="},
+		{"{&var:3}", "&var=val"},
+		{"{&list}", "&list=red,green,blue"},
+		{"{&list*}", "&list=red&list=green&list=blue"},
+		{"{&keys}", "&keys=comma,%2C,dot,.,semi,%3B"},
+		{"{&keys*}", "&comma=%2C&dot=.&semi=%3B"},
+
+		// 已编码的三元组只在保留展开中保留
+		{"{+pct}", "a%2Fb%25zz%41"},
+		{"{pct}", "a%252Fb%25zz%2541"},
+
+		{"https://api.example.com/users/{id}/repos{?x,y}", "https://api.example.com/users/42/repos?x=1024&y=768"},
+		{"no expression", "no expression"},
+	}
+
+	vars := map[string]interface{}{"pct": "a%2Fb%zz%41"}
+	for k, v := range templateVars {
+		vars[k] = v
+	}
+
+	for _, tt := range tests {
+		got, err := expandURITemplate(tt.tmpl, vars)
+		if err != nil || got != tt.want {
+			t.Errorf("expandURITemplate(%q) = %q, %v, want %q", tt.tmpl, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandURITemplateErrors(t *testing.T) {
+	tests := []string{
+		"{var",
+		"var}",
+		"/users/{id}}",
+		"{}",
+		"{+}",
+		"{var:}",
+		"{var:0}",
+		"{var:-1}",
+		"{var:abc}",
+		"{var:10000}",
+		"{*}",
+		"{x,}",
+	}
+
+	for _, tmpl := range tests {
+		if got, err := expandURITemplate(tmpl, templateVars); err == nil {
+			t.Errorf("expandURITemplate(%q) = %q, want an error", tmpl, got)
+		}
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	mt := mock.NewTransport()
+	mt.On("GET", "/repos/*").Reply(200)
+
+	s := NewSession().SetBaseURL("https://api.test").SetTransport(mt)
+
+	_, err := s.To("GET", "/repos/{owner}/{repo}/issues{?labels,page}").
+		PathParam("owner", "lets-go-go").
+		PathParams(map[string]interface{}{"repo": "http client", "labels": []string{"bug", "p1"}}).
+		AddQuery("page", "2").
+		Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := mt.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("%d requests sent, want 1", len(calls))
+	}
+
+	u := calls[0].Request.URL
+	if got, want := u.EscapedPath(), "/repos/lets-go-go/http%20client/issues"; got != want {
+		t.Errorf("path = %q, want %q", got, want)
+	}
+	if got, want := u.Query().Get("labels"), "bug,p1"; got != want {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+	if got, want := u.Query().Get("page"), "2"; got != want {
+		t.Errorf("page = %q, want %q", got, want)
+	}
+}
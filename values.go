@@ -0,0 +1,333 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encoder is implemented by types which know how to encode themselves into
+// url.Values. EncodeValues is called with the key the value is stored under.
+type Encoder interface {
+	EncodeValues(key string, v *url.Values) error
+}
+
+var (
+	encoderType = reflect.TypeOf(new(Encoder)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// StructValues returns the url.Values encoding of the struct v, which may
+// also be a pointer to a struct. Fields are encoded according to their "url"
+// struct tag:
+//
+//	Name  string    `url:"name"`            // name=...
+//	Page  int       `url:"page,omitempty"`  // skipped when zero
+//	Tags  []string  `url:"tags"`            // tags=a&tags=b
+//	IDs   []int     `url:"ids,comma"`       // ids=1,2 (also space, semicolon)
+//	Sort  []string  `url:"sort,brackets"`   // sort[]=a&sort[]=b
+//	Keys  []string  `url:"key,numbered"`    // key0=a&key1=b
+//	Since time.Time `url:"since"`           // RFC 3339
+//	Day   time.Time `url:"day" layout:"2006-01-02"`
+//	At    time.Time `url:"at,unix"`         // also unixmilli
+//	Debug bool      `url:"debug,int"`       // 1 or 0
+//	User  User      `url:"user"`            // user[name]=...
+//	Owner User      `url:"owner,dot"`       // owner.name=...
+//	Skip  string    `url:"-"`
+//
+// Fields without a tag use the field name, anonymous struct fields are
+// flattened into the parent and nil pointers are skipped. Values which
+// implement Encoder encode themselves.
+func StructValues(v interface{}) (url.Values, error) {
+	values := make(url.Values)
+
+	if v == nil {
+		return values, nil
+	}
+
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return values, nil
+		}
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("request: StructValues expects struct input, got %v", val.Kind())
+	}
+
+	err := reflectStruct(values, val, "", false)
+	return values, err
+}
+
+// QueryStruct adds the fields of the struct v to request's URL query-string.
+// See StructValues for the supported struct tags.
+func (c *Client) QueryStruct(v interface{}) *Client {
+	vals, err := StructValues(v)
+
+	if err != nil {
+		c.err = err
+		return c
+	}
+
+	return c.SetQuery(vals)
+}
+
+// FormStruct adds the fields of the struct v as form fields, like AddFields.
+// See StructValues for the supported struct tags.
+func (c *Client) FormStruct(v interface{}) *Client {
+	vals, err := StructValues(v)
+
+	if err != nil {
+		c.err = err
+		return c
+	}
+
+	return c.AddFields(vals)
+}
+
+type tagOptions []string
+
+func (o tagOptions) contains(option string) bool {
+	for _, s := range o {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTag(tag string) (string, tagOptions) {
+	s := strings.Split(tag, ",")
+	return s[0], s[1:]
+}
+
+func nestedKey(scope, name string, dot bool) string {
+	if scope == "" {
+		return name
+	}
+
+	if dot {
+		return scope + "." + name
+	}
+
+	return scope + "[" + name + "]"
+}
+
+func reflectStruct(values url.Values, val reflect.Value, scope string, dot bool) error {
+	typ := val.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		tag := sf.Tag.Get("url")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+		sv := val.Field(i)
+
+		if name == "" {
+			if sf.Anonymous {
+				ev := reflect.Indirect(sv)
+				if ev.Kind() == reflect.Struct && ev.Type() != timeType && !implementsEncoder(sv) {
+					if err := reflectStruct(values, ev, scope, dot); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			name = sf.Name
+		}
+
+		// 未导出类型的嵌入结构体仍可能有导出字段，与 encoding/json 一致
+		if sf.PkgPath != "" && !isEmbeddedStruct(sf) {
+			continue
+		}
+
+		if opts.contains("omitempty") && isEmptyValue(sv) {
+			continue
+		}
+
+		if err := reflectValue(values, sv, nestedKey(scope, name, dot), opts, sf.Tag, dot || opts.contains("dot")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func reflectValue(values url.Values, sv reflect.Value, name string, opts tagOptions, tag reflect.StructTag, dot bool) error {
+	if enc, ok := encoderOf(sv); ok {
+		return enc.EncodeValues(name, &values)
+	}
+
+	for sv.Kind() == reflect.Ptr || sv.Kind() == reflect.Interface {
+		if sv.IsNil() {
+			return nil
+		}
+		sv = sv.Elem()
+
+		if enc, ok := encoderOf(sv); ok {
+			return enc.EncodeValues(name, &values)
+		}
+	}
+
+	switch sv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if sv.Kind() == reflect.Slice && sv.Type().Elem().Kind() == reflect.Uint8 {
+			values.Add(name, string(sv.Bytes()))
+			return nil
+		}
+		return reflectSlice(values, sv, name, opts, tag, dot)
+	case reflect.Map:
+		if sv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("request: unsupported map key type %v for %q", sv.Type().Key(), name)
+		}
+		iter := sv.MapRange()
+		for iter.Next() {
+			if err := reflectValue(values, iter.Value(), nestedKey(name, iter.Key().String(), dot), opts, tag, dot); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		if sv.Type() != timeType {
+			return reflectStruct(values, sv, name, dot)
+		}
+	}
+
+	values.Add(name, valueString(sv, opts, tag))
+	return nil
+}
+
+func reflectSlice(values url.Values, sv reflect.Value, name string, opts tagOptions, tag reflect.StructTag, dot bool) error {
+	var sep string
+
+	switch {
+	case opts.contains("comma"):
+		sep = ","
+	case opts.contains("space"):
+		sep = " "
+	case opts.contains("semicolon"):
+		sep = ";"
+	}
+
+	if sep != "" {
+		s := make([]string, sv.Len())
+		for i := range s {
+			s[i] = valueString(reflect.Indirect(sv.Index(i)), opts, tag)
+		}
+		values.Add(name, strings.Join(s, sep))
+		return nil
+	}
+
+	for i := 0; i < sv.Len(); i++ {
+		key := name
+		switch {
+		case opts.contains("brackets"):
+			key = name + "[]"
+		case opts.contains("numbered"):
+			key = name + strconv.Itoa(i)
+		}
+
+		if err := reflectValue(values, sv.Index(i), key, opts, tag, dot); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func valueString(v reflect.Value, opts tagOptions, tag reflect.StructTag) string {
+	if !v.IsValid() {
+		return ""
+	}
+
+	if v.Kind() == reflect.Bool && opts.contains("int") {
+		if v.Bool() {
+			return "1"
+		}
+		return "0"
+	}
+
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		switch {
+		case opts.contains("unix"):
+			return strconv.FormatInt(t.Unix(), 10)
+		case opts.contains("unixmilli"):
+			return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+		case tag.Get("layout") != "":
+			return t.Format(tag.Get("layout"))
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+func isEmbeddedStruct(sf reflect.StructField) bool {
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return sf.Anonymous && t.Kind() == reflect.Struct
+}
+
+func implementsEncoder(v reflect.Value) bool {
+	_, ok := encoderOf(v)
+	return ok
+}
+
+func encoderOf(v reflect.Value) (Encoder, bool) {
+	if !v.IsValid() {
+		return nil, false
+	}
+
+	if v.Type().Implements(encoderType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, false
+		}
+		if v.CanInterface() {
+			return v.Interface().(Encoder), true
+		}
+	}
+
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(encoderType) && v.Addr().CanInterface() {
+		return v.Addr().Interface().(Encoder), true
+	}
+
+	return nil, false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+
+	return false
+}
@@ -0,0 +1,253 @@
+package httpclient
+
+import (
+	"io"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type valuesPage struct {
+	Page int `url:"page"`
+	Size int `url:"size,omitempty"`
+}
+
+type valuesUser struct {
+	Name string `url:"name"`
+	Age  int    `url:"age,omitempty"`
+}
+
+type valuesColor int
+
+func (c valuesColor) EncodeValues(key string, v *url.Values) error {
+	v.Set(key, [...]string{"red", "green", "blue"}[c])
+	return nil
+}
+
+func TestStructValues(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	one, empty := 1, ""
+
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, ""},
+		{(*valuesPage)(nil), ""},
+		{valuesPage{Page: 2}, "page=2"},
+		{&valuesPage{Page: 2, Size: 50}, "page=2&size=50"},
+
+		// omitempty 只跳过零值
+		{struct {
+			S string            `url:"s,omitempty"`
+			I int               `url:"i,omitempty"`
+			B bool              `url:"b,omitempty"`
+			F float64           `url:"f,omitempty"`
+			L []string          `url:"l,omitempty"`
+			M map[string]string `url:"m,omitempty"`
+			P *int              `url:"p,omitempty"`
+			T time.Time         `url:"t,omitempty"`
+		}{}, ""},
+		{struct {
+			S string `url:"s"`
+			I int    `url:"i"`
+			B bool   `url:"b"`
+		}{}, "b=false&i=0&s="},
+		{struct {
+			P *string `url:"p,omitempty"`
+		}{&empty}, "p="},
+
+		// 指针
+		{struct {
+			P *int `url:"p"`
+		}{}, ""},
+		{struct {
+			P *int `url:"p"`
+		}{&one}, "p=1"},
+		{struct {
+			P **int `url:"p"`
+		}{func() **int { p := &one; return &p }()}, "p=1"},
+		{struct {
+			U *valuesUser `url:"user"`
+		}{&valuesUser{Name: "ann"}}, "user%5Bname%5D=ann"},
+		{struct {
+			V interface{} `url:"v"`
+		}{&one}, "v=1"},
+
+		// 切片
+		{struct {
+			L []string `url:"l"`
+		}{[]string{"a", "b"}}, "l=a&l=b"},
+		{struct {
+			L []string `url:"l"`
+		}{[]string{}}, ""},
+		{struct {
+			L []int `url:"l,comma"`
+		}{[]int{1, 2, 3}}, "l=1%2C2%2C3"},
+		{struct {
+			L []string `url:"l,space"`
+		}{[]string{"a", "b"}}, "l=a+b"},
+		{struct {
+			L []string `url:"l,semicolon"`
+		}{[]string{"a", "b"}}, "l=a%3Bb"},
+		{struct {
+			L []string `url:"l,brackets"`
+		}{[]string{"a", "b"}}, "l%5B%5D=a&l%5B%5D=b"},
+		{struct {
+			L []string `url:"l,numbered"`
+		}{[]string{"a", "b"}}, "l0=a&l1=b"},
+		{struct {
+			L []*int `url:"l,comma"`
+		}{[]*int{&one, &one}}, "l=1%2C1"},
+		{struct {
+			L [2]int `url:"l"`
+		}{[2]int{4, 5}}, "l=4&l=5"},
+		{struct {
+			B []byte `url:"b"`
+		}{[]byte("raw")}, "b=raw"},
+		{struct {
+			L []valuesUser `url:"u,brackets"`
+		}{[]valuesUser{{Name: "a"}, {Name: "b"}}}, "u%5B%5D%5Bname%5D=a&u%5B%5D%5Bname%5D=b"},
+
+		// 嵌入结构体
+		{struct {
+			valuesPage
+			Q string `url:"q"`
+		}{valuesPage{Page: 3}, "go"}, "page=3&q=go"},
+		{struct {
+			*valuesPage
+			Q string `url:"q"`
+		}{Q: "go"}, "q=go"},
+		{struct {
+			*valuesPage
+			Q string `url:"q"`
+		}{&valuesPage{Page: 3}, "go"}, "page=3&q=go"},
+		{struct {
+			valuesPage `url:"p"`
+		}{valuesPage{Page: 3}}, "p%5Bpage%5D=3"},
+		{struct {
+			*valuesPage `url:"p"`
+		}{&valuesPage{Page: 3}}, "p%5Bpage%5D=3"},
+		{struct {
+			valuesUser `url:"u,dot"`
+		}{valuesUser{Name: "ann", Age: 7}}, "u.age=7&u.name=ann"},
+
+		// time.Time
+		{struct {
+			T time.Time `url:"t"`
+		}{at}, "t=2024-03-01T12%3A30%3A00Z"},
+		{struct {
+			T time.Time `url:"t" layout:"2006-01-02"`
+		}{at}, "t=2024-03-01"},
+		{struct {
+			T time.Time `url:"t,unix"`
+		}{at}, "t=1709296200"},
+		{struct {
+			T time.Time `url:"t,unixmilli"`
+		}{at}, "t=1709296200000"},
+		{struct {
+			T *time.Time `url:"t,unix"`
+		}{&at}, "t=1709296200"},
+		{struct {
+			T *time.Time `url:"t"`
+		}{}, ""},
+		{struct {
+			time.Time
+		}{at}, "Time=2024-03-01T12%3A30%3A00Z"},
+
+		// "-" 标签
+		{struct {
+			A string `url:"-"`
+			B string `url:"b"`
+		}{"a", "b"}, "b=b"},
+		{struct {
+			A string `url:"-,"`
+		}{"a"}, "-=a"},
+		{struct {
+			A string
+			b string
+		}{"a", "b"}, "A=a"},
+
+		{struct {
+			D bool `url:"d,int"`
+			E bool `url:"e,int"`
+		}{true, false}, "d=1&e=0"},
+		{struct {
+			M map[string]int `url:"m"`
+		}{map[string]int{"x": 1}}, "m%5Bx%5D=1"},
+		{struct {
+			C valuesColor `url:"c"`
+		}{2}, "c=blue"},
+	}
+
+	for i, tt := range tests {
+		got, err := StructValues(tt.v)
+		if err != nil || got.Encode() != tt.want {
+			t.Errorf("%d: StructValues(%+v) = %q, %v, want %q", i, tt.v, got.Encode(), err, tt.want)
+		}
+	}
+}
+
+func TestStructValuesErrors(t *testing.T) {
+	tests := []interface{}{
+		1,
+		"page=1",
+		[]valuesPage{{Page: 1}},
+		map[string]string{"page": "1"},
+		struct {
+			M map[int]string `url:"m"`
+		}{map[int]string{1: "a"}},
+	}
+
+	for _, v := range tests {
+		if got, err := StructValues(v); err == nil {
+			t.Errorf("StructValues(%+v) = %q, want an error", v, got.Encode())
+		}
+	}
+}
+
+func TestQueryStruct(t *testing.T) {
+	req, err := New().To("GET", "https://api.test/search?q=go").
+		QueryStruct(&struct {
+			valuesPage
+			Tags []string `url:"tag,brackets"`
+			Skip string   `url:"-"`
+		}{valuesPage{Page: 2}, []string{"a", "b"}, "x"}).
+		Req()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := req.URL.Query().Encode(), "page=2&q=go&tag%5B%5D=a&tag%5B%5D=b"; got != want {
+		t.Errorf("query = %q, want %q", got, want)
+	}
+
+	if _, err := New().To("GET", "https://api.test").QueryStruct(1).Req(); err == nil {
+		t.Error("QueryStruct(1) did not fail")
+	}
+}
+
+func TestFormStruct(t *testing.T) {
+	req, err := New().To("POST", "https://api.test/users").
+		FormStruct(valuesUser{Name: "ann"}).
+		Req()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := req.Header.Get("Content-Type"), "application/x-www-form-urlencoded"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(body), "name=ann"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	if _, err := New().To("POST", "https://api.test").FormStruct([]string{"a"}).Req(); err == nil {
+		t.Error("FormStruct([]string) did not fail")
+	}
+}
@@ -0,0 +1,150 @@
+package vcr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cassette is the list of recorded interactions stored in a file.
+type Cassette struct {
+	Version      int            `json:"version" yaml:"version"`
+	Interactions []*Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request    Request       `json:"request" yaml:"request"`
+	Response   Response      `json:"response" yaml:"response"`
+	RecordedAt time.Time     `json:"recorded_at" yaml:"recorded_at"`
+	Duration   time.Duration `json:"duration" yaml:"duration"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method string      `json:"method" yaml:"method"`
+	URL    string      `json:"url" yaml:"url"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   Body        `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Response is a recorded response, its body is stored as received on the
+// wire, like Response.Raw returns it.
+type Response struct {
+	Status     string      `json:"status" yaml:"status"`
+	StatusCode int         `json:"status_code" yaml:"status_code"`
+	Proto      string      `json:"proto" yaml:"proto"`
+	Header     http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       Body        `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Body is a recorded body. Text bodies are stored as is, binary bodies are
+// stored base64 encoded with a "base64:" prefix.
+type Body []byte
+
+const base64Prefix = "base64:"
+
+func (b Body) String() string {
+	if utf8.Valid(b) && !strings.HasPrefix(string(b), base64Prefix) {
+		return string(b)
+	}
+	return base64Prefix + base64.StdEncoding.EncodeToString(b)
+}
+
+func (b *Body) decode(s string) error {
+	if !strings.HasPrefix(s, base64Prefix) {
+		*b = Body(s)
+		return nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(s[len(base64Prefix):])
+	*b = raw
+	return err
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b Body) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return b.decode(s)
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (b Body) MarshalYAML() (interface{}, error) {
+	return b.String(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (b *Body) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	return b.decode(s)
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Load reads a cassette from path, the format is YAML for ".yaml" and
+// ".yml" files and JSON otherwise.
+func Load(path string) (*Cassette, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(Cassette)
+	if isYAML(path) {
+		err = yaml.Unmarshal(b, c)
+	} else {
+		err = json.Unmarshal(b, c)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Save writes the cassette to path, creating its directory if needed.
+func (c *Cassette) Save(path string) error {
+	var (
+		b   []byte
+		err error
+	)
+
+	if isYAML(path) {
+		b, err = yaml.Marshal(c)
+	} else {
+		b, err = json.MarshalIndent(c, "", "  ")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0644)
+}
@@ -0,0 +1,463 @@
+// Package vcr records the HTTP interactions of a session into cassette
+// files and replays them, so integration tests can run offline and
+// deterministically:
+//
+//	rec, err := vcr.New("testdata/github.yaml", vcr.ModeReplayOrRecord)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer rec.Stop()
+//
+//	rec.RedactHeaders("Authorization").RedactJSONFields("token")
+//	session := httpclient.NewSession().SetTransport(rec)
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lets-go-go/httpclient"
+)
+
+// Mode is the operating mode of a Recorder.
+type Mode int
+
+// Recorder modes.
+const (
+	ModeReplay         Mode = iota // 只回放，没有匹配的记录时返回 ErrNoInteraction
+	ModeRecord                     // 总是访问网络并重新录制
+	ModeReplayOrRecord             // 有匹配的记录时回放，否则访问网络并追加录制
+	ModeDisabled                   // 直接访问网络，不录制
+)
+
+// ErrNoInteraction is returned in replay mode when no recorded interaction
+// matches the request.
+var ErrNoInteraction = errors.New("vcr: no recorded interaction matches the request")
+
+// Matcher reports whether the recorded request i matches r. The request has
+// already been redacted like the recorded one.
+type Matcher func(r *Request, i *Request) bool
+
+// MatchMethodAndURL is the default Matcher.
+func MatchMethodAndURL(r *Request, i *Request) bool {
+	return r.Method == i.Method && r.URL == i.URL
+}
+
+// MatchBody compares the request bodies, JSON bodies are compared
+// semantically.
+func MatchBody(r *Request, i *Request) bool {
+	if bytes.Equal(r.Body, i.Body) {
+		return true
+	}
+
+	var a, b interface{}
+	if json.Unmarshal(r.Body, &a) != nil || json.Unmarshal(i.Body, &b) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// MatchHeaders returns a Matcher comparing the given request headers.
+func MatchHeaders(names ...string) Matcher {
+	return func(r *Request, i *Request) bool {
+		for _, name := range names {
+			if r.Header.Get(name) != i.Header.Get(name) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchAll returns a Matcher requiring all matchers to match.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(r *Request, i *Request) bool {
+		for _, m := range matchers {
+			if !m(r, i) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Recorder is an http.RoundTripper recording and replaying interactions.
+type Recorder struct {
+	mu        sync.Mutex
+	path      string
+	mode      Mode
+	cassette  *Cassette
+	used      map[*Interaction]bool
+	dirty     bool
+	transport http.RoundTripper
+	matcher   Matcher
+	repeat    bool
+
+	headers    map[string]bool
+	query      map[string]bool
+	jsonFields map[string]bool
+	hooks      []func(*Interaction)
+}
+
+// New returns a Recorder for the cassette at path, which is loaded unless
+// the mode is ModeRecord. A missing cassette is an error in ModeReplay.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{
+		path:       path,
+		mode:       mode,
+		cassette:   &Cassette{Version: 1},
+		used:       make(map[*Interaction]bool),
+		matcher:    MatchMethodAndURL,
+		headers:    make(map[string]bool),
+		query:      make(map[string]bool),
+		jsonFields: make(map[string]bool),
+	}
+
+	if mode == ModeRecord || mode == ModeDisabled {
+		return r, nil
+	}
+
+	c, err := Load(path)
+
+	switch {
+	case err == nil:
+		r.cassette = c
+	case os.IsNotExist(err) && mode == ModeReplayOrRecord:
+	default:
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// SetTransport sets the transport used to reach the network when recording.
+// It defaults to the transport configured by httpclient.Settings().SetProxy,
+// or http.DefaultTransport.
+func (r *Recorder) SetTransport(rt http.RoundTripper) *Recorder {
+	r.transport = rt
+	return r
+}
+
+// SetMatcher sets how requests are matched against recorded interactions.
+func (r *Recorder) SetMatcher(m Matcher) *Recorder {
+	r.matcher = m
+	return r
+}
+
+// AllowRepeats lets an interaction be replayed more than once. By default
+// every interaction is used once, in recording order.
+func (r *Recorder) AllowRepeats() *Recorder {
+	r.repeat = true
+	return r
+}
+
+// RedactHeaders replaces the values of the request and response headers
+// with httpclient.Redacted before they are saved. Cookies are redacted with
+// RedactHeaders("Cookie", "Set-Cookie").
+func (r *Recorder) RedactHeaders(names ...string) *Recorder {
+	for _, n := range names {
+		r.headers[http.CanonicalHeaderKey(n)] = true
+	}
+	return r
+}
+
+// RedactQuery replaces the values of the query parameters with
+// httpclient.Redacted.
+func (r *Recorder) RedactQuery(names ...string) *Recorder {
+	for _, n := range names {
+		r.query[n] = true
+	}
+	return r
+}
+
+// RedactJSONFields replaces the values of the JSON object fields with the
+// given names, at any depth, in request and response bodies. Request bodies
+// compressed by SetCompression are recorded uncompressed, the other bodies
+// with a Content-Encoding cannot be redacted and recording them fails.
+func (r *Recorder) RedactJSONFields(names ...string) *Recorder {
+	for _, n := range names {
+		r.jsonFields[n] = true
+	}
+	return r
+}
+
+// AddHook adds a function called on every interaction before it is saved,
+// after the built-in redaction.
+func (r *Recorder) AddHook(fn func(*Interaction)) *Recorder {
+	r.hooks = append(r.hooks, fn)
+	return r
+}
+
+// Cassette returns the cassette of the recorder.
+func (r *Recorder) Cassette() *Cassette {
+	return r.cassette
+}
+
+// Stop saves the cassette if new interactions were recorded.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+
+	r.dirty = false
+	return r.cassette.Save(r.path)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeDisabled {
+		return r.realTransport().RoundTrip(req)
+	}
+
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// 录制压缩前的请求体，才能脱敏和比较
+	if getBody := httpclient.UncompressedBody(req); getBody != nil {
+		if body, err = readUncompressed(getBody); err != nil {
+			return nil, err
+		}
+	} else if err = r.checkRedactable(req.Header, body); err != nil {
+		return nil, err
+	}
+
+	rec := r.redactRequest(Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: cloneHeader(req.Header),
+		Body:   body,
+	})
+
+	if r.mode != ModeRecord {
+		if i := r.find(&rec); i != nil {
+			return i.Response.toHTTP(req), nil
+		}
+
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, rec.URL)
+		}
+	}
+
+	start := time.Now()
+	res, err := r.realTransport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := readBody(&res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.checkRedactable(res.Header, raw); err != nil {
+		return nil, err
+	}
+
+	i := &Interaction{
+		Request: rec,
+		Response: Response{
+			Status:     res.Status,
+			StatusCode: res.StatusCode,
+			Proto:      res.Proto,
+			Header:     cloneHeader(res.Header),
+			Body:       raw,
+		},
+		RecordedAt: start.UTC(),
+		Duration:   time.Since(start),
+	}
+
+	r.redactResponse(&i.Response)
+	for _, fn := range r.hooks {
+		fn(i)
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	r.used[i] = true
+	r.dirty = true
+	r.mu.Unlock()
+
+	return res, nil
+}
+
+func (r *Recorder) realTransport() http.RoundTripper {
+	if r.transport != nil {
+		return r.transport
+	}
+
+	if t := httpclient.Settings().ProxyTransport; t != nil {
+		return t
+	}
+
+	return http.DefaultTransport
+}
+
+func (r *Recorder) find(req *Request) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, i := range r.cassette.Interactions {
+		if (r.repeat || !r.used[i]) && r.matcher(req, &i.Request) {
+			r.used[i] = true
+			return i
+		}
+	}
+
+	return nil
+}
+
+func (r *Recorder) redactRequest(req Request) Request {
+	if len(r.query) > 0 {
+		if u, err := url.Parse(req.URL); err == nil {
+			q := u.Query()
+			for k, vs := range q {
+				if r.query[k] {
+					for i := range vs {
+						vs[i] = httpclient.Redacted
+					}
+				}
+			}
+			u.RawQuery = q.Encode()
+			req.URL = u.String()
+		}
+	}
+
+	r.redactHeader(req.Header)
+	req.Body = r.redactJSON(req.Body)
+
+	return req
+}
+
+func (r *Recorder) redactResponse(res *Response) {
+	r.redactHeader(res.Header)
+
+	if len(r.jsonFields) > 0 {
+		n := len(res.Body)
+		if res.Body = r.redactJSON(res.Body); len(res.Body) != n && res.Header.Get("Content-Length") != "" {
+			res.Header.Set("Content-Length", strconv.Itoa(len(res.Body)))
+		}
+	}
+}
+
+// checkRedactable returns an error when the JSON fields of body must be
+// redacted but it is encoded, since it would be saved unredacted.
+func (r *Recorder) checkRedactable(h http.Header, body Body) error {
+	if len(r.jsonFields) == 0 || len(body) == 0 {
+		return nil
+	}
+
+	if enc := h.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return fmt.Errorf("vcr: cannot redact the JSON fields of a body with Content-Encoding %q", enc)
+	}
+
+	return nil
+}
+
+func (r *Recorder) redactHeader(h http.Header) {
+	for k, vs := range h {
+		if r.headers[k] {
+			for i := range vs {
+				vs[i] = httpclient.Redacted
+			}
+		}
+	}
+}
+
+func (r *Recorder) redactJSON(body Body) Body {
+	if len(r.jsonFields) == 0 || len(body) == 0 {
+		return body
+	}
+
+	var v interface{}
+	if json.Unmarshal(body, &v) != nil {
+		return body
+	}
+
+	if !httpclient.RedactJSONValue(v, r.jsonFields) {
+		return body
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+
+	return b
+}
+
+func (res *Response) toHTTP(req *http.Request) *http.Response {
+	major, minor, ok := http.ParseHTTPVersion(res.Proto)
+	if !ok {
+		major, minor = 1, 1
+	}
+
+	return &http.Response{
+		Status:        res.Status,
+		StatusCode:    res.StatusCode,
+		Proto:         res.Proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        cloneHeader(res.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(res.Body)),
+		ContentLength: int64(len(res.Body)),
+		Request:       req,
+	}
+}
+
+// readBody reads *body and replaces it with a fresh reader over the same
+// bytes.
+func readBody(body *io.ReadCloser) (Body, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadAll(*body)
+	(*body).Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	*body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+// readUncompressed reads the body returned by getBody.
+func readUncompressed(getBody func() (io.ReadCloser, error)) (Body, error) {
+	body, err := getBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+func cloneHeader(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+
+	c := make(http.Header, len(h))
+	for k, vs := range h {
+		c[k] = append([]string(nil), vs...)
+	}
+	return c
+}
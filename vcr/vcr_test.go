@@ -0,0 +1,238 @@
+package vcr
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lets-go-go/httpclient"
+	"github.com/lets-go-go/httpclient/mock"
+)
+
+// upstream returns the transport standing for the network while recording.
+func upstream() *mock.Transport {
+	mt := mock.NewTransport().Strict()
+	mt.On("POST", "/login").ReplyJSON(200, map[string]interface{}{"token": "t0p", "user": map[string]string{"name": "bob", "token": "n3sted"}})
+	mt.On("GET", "/users/*").ReplyHeader("Set-Cookie", "sid=1").ReplyString(200, "hello")
+	mt.On("GET", "/avatar").ReplyHeader("Content-Type", "image/png").ReplyString(200, "\x89PNG\x00\xff")
+	return mt
+}
+
+type exchange struct {
+	method, url, body string
+	status            int
+	response          string
+}
+
+var exchanges = []exchange{
+	{"POST", "http://api.test/login?key=s3cret", `{"password":"pw","name":"bob"}`, 200, ""},
+	{"GET", "http://api.test/users/bob", "", 200, "hello"},
+	{"GET", "http://api.test/avatar", "", 200, "\x89PNG\x00\xff"},
+}
+
+// redact sets the redaction policy, which the recorder also applies to the
+// requests it replays.
+func redact(r *Recorder) *Recorder {
+	return r.RedactHeaders("Authorization", "Set-Cookie").
+		RedactQuery("key").
+		RedactJSONFields("password", "token")
+}
+
+func send(t *testing.T, rt http.RoundTripper, e exchange) *httpclient.Response {
+	t.Helper()
+
+	req := httpclient.NewSession().SetTransport(rt).To(e.method, e.url).
+		SetHeader("Authorization", "Bearer t0k3n")
+	if e.body != "" {
+		req.SendBody(e.body)
+	}
+
+	res, err := req.Execute()
+	if err != nil {
+		t.Fatalf("%s %s: %v", e.method, e.url, err)
+	}
+	return res
+}
+
+func TestRecordReplay(t *testing.T) {
+	for _, name := range []string{"cassette.yaml", "cassette.json"} {
+		path := filepath.Join(t.TempDir(), "testdata", name)
+
+		rec, err := New(path, ModeRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		redact(rec).SetTransport(upstream())
+
+		for _, e := range exchanges {
+			send(t, rec, e).Raw()
+		}
+
+		if err := rec.Stop(); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"t0k3n", "s3cret", `"pw"`, "t0p", "n3sted", "sid=1"} {
+			if bytes.Contains(raw, []byte(secret)) {
+				t.Errorf("%s: cassette contains %s:\n%s", name, secret, raw)
+			}
+		}
+
+		// 回放时不访问网络
+		rep, err := New(path, ModeReplay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		redact(rep).SetTransport(mock.NewTransport().Strict())
+
+		for i, e := range exchanges {
+			res := send(t, rep, e)
+			b, _ := res.Raw()
+
+			want := rec.Cassette().Interactions[i].Response.Body
+			if res.StatusCode != e.status || !bytes.Equal(b, want) {
+				t.Errorf("%s: replayed %s %s = %d %q, want %d %q", name, e.method, e.url, res.StatusCode, b, e.status, want)
+			}
+			if e.response != "" && string(b) != e.response {
+				t.Errorf("%s: replayed body %q, want %q", name, b, e.response)
+			}
+		}
+
+		// 每条记录只回放一次
+		_, err = httpclient.NewSession().SetTransport(rep).To("GET", exchanges[1].url).Execute()
+		if !errors.Is(err, ErrNoInteraction) {
+			t.Errorf("%s: second replay = %v, want ErrNoInteraction", name, err)
+		}
+
+		if err := rep.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReplayRedactedJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "login.json")
+
+	rec, _ := New(path, ModeRecord)
+	rec.SetTransport(upstream()).RedactJSONFields("token")
+
+	res := send(t, rec, exchanges[0])
+	res.Raw()
+	rec.Stop()
+
+	rep, err := New(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Token string
+		User  struct{ Name, Token string }
+	}
+	if err := send(t, rep, exchanges[0]).Decode(&out, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Token != httpclient.Redacted || out.User.Token != httpclient.Redacted || out.User.Name != "bob" {
+		t.Errorf("replayed body = %+v, want the tokens redacted", out)
+	}
+}
+
+func TestReplayOrRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.yaml")
+	up := upstream()
+
+	for i := 0; i < 2; i++ {
+		rec, err := New(path, ModeReplayOrRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.SetTransport(up)
+
+		send(t, rec, exchanges[1]).Raw()
+		if err := rec.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := len(up.Calls()); n != 1 {
+		t.Errorf("%d requests reached the network, want 1", n)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 1 {
+		t.Errorf("%d interactions recorded, want 1", len(c.Interactions))
+	}
+}
+
+func TestAllowRepeats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.json")
+
+	rec, _ := New(path, ModeRecord)
+	rec.SetTransport(upstream())
+	send(t, rec, exchanges[1]).Raw()
+	rec.Stop()
+
+	rep, _ := New(path, ModeReplay)
+	rep.AllowRepeats()
+	for i := 0; i < 3; i++ {
+		if b, _ := send(t, rep, exchanges[1]).Raw(); string(b) != "hello" {
+			t.Errorf("replay %d = %q, want hello", i, b)
+		}
+	}
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing.yaml"), ModeReplay); !os.IsNotExist(err) {
+		t.Errorf("New = %v, want a not exist error", err)
+	}
+}
+
+// Compressed request bodies are recorded readable and matched by MatchBody.
+func TestRecordCompressedBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.json")
+	body := `{"name":"` + strings.Repeat("a", 2048) + `"}`
+
+	mt := mock.NewTransport()
+	mt.On("POST", "/items").Reply(201)
+
+	rec, _ := New(path, ModeRecord)
+	rec.SetTransport(mt)
+
+	_, err := httpclient.NewSession().SetTransport(rec).
+		SetCompression(httpclient.Compression{Encoding: "gzip"}).
+		To("POST", "http://api.test/items").SendBody(body).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Stop()
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(c.Interactions[0].Request.Body); got != body {
+		t.Errorf("recorded body = %.40q, want the uncompressed body", got)
+	}
+
+	rep, _ := New(path, ModeReplay)
+	rep.SetMatcher(MatchAll(MatchMethodAndURL, MatchBody))
+
+	res, err := httpclient.NewSession().SetTransport(rep).
+		To("POST", "http://api.test/items").SendBody(body).Execute()
+	if err != nil || res.StatusCode != 201 {
+		t.Errorf("replay = %v, %v, want 201", res, err)
+	}
+}
@@ -0,0 +1,562 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the type of a WebSocket data message.
+type MessageType int
+
+// The WebSocket data message types.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// WebSocket close codes defined by RFC 6455.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseAbnormal        = 1006
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// ErrWebSocketClosed is returned when using a WebSocket after Close.
+var ErrWebSocketClosed = errors.New("request: websocket closed")
+
+// CloseError is returned by ReadMessage when the server closes the
+// connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("request: websocket closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("request: websocket closed with code %d: %s", e.Code, e.Reason)
+}
+
+// WebSocket is a client WebSocket connection opened by Client.WebSocket.
+//
+// One goroutine may read and others write concurrently: pings are answered
+// and close frames acknowledged while reading, so a connection must be read
+// for as long as it is used.
+type WebSocket struct {
+	conn         net.Conn
+	br           *bufio.Reader
+	protocol     string
+	compress     bool
+	readLimit    int64
+	fragmentSize int
+
+	readMu sync.Mutex
+
+	writeMu sync.Mutex
+	closing bool // close 帧已发送
+
+	mu       sync.Mutex
+	onPong   func(data []byte)
+	closeErr *CloseError
+	gotClose chan struct{} // 收到对端的 close 帧后关闭
+}
+
+// Subprotocol returns the subprotocol selected by the server, if any.
+func (ws *WebSocket) Subprotocol() string {
+	return ws.protocol
+}
+
+// Compressed reports whether permessage-deflate was negotiated.
+func (ws *WebSocket) Compressed() bool {
+	return ws.compress
+}
+
+// LocalAddr returns the local network address.
+func (ws *WebSocket) LocalAddr() net.Addr {
+	return ws.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (ws *WebSocket) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline for the pending and future reads.
+func (ws *WebSocket) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for the pending and future writes.
+func (ws *WebSocket) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// OnPong sets the function called with the payload of every pong received.
+func (ws *WebSocket) OnPong(fn func(data []byte)) *WebSocket {
+	ws.mu.Lock()
+	ws.onPong = fn
+	ws.mu.Unlock()
+	return ws
+}
+
+// Ping sends a ping with data, at most 125 bytes. The pong is reported to
+// the OnPong function while reading.
+func (ws *WebSocket) Ping(data []byte) error {
+	if len(data) > 125 {
+		return errors.New("request: websocket control frame payload larger than 125 bytes")
+	}
+	return ws.writeFrame(true, false, opPing, data)
+}
+
+// WriteMessage sends a message, compressed when permessage-deflate was
+// negotiated and split in frames of the configured FragmentSize.
+func (ws *WebSocket) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("request: invalid websocket message type %d", typ)
+	}
+
+	compressed := false
+	if ws.compress && len(data) > 0 {
+		var err error
+		if data, err = deflateMessage(data); err != nil {
+			return err
+		}
+		compressed = true
+	}
+
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	if ws.closing {
+		return ErrWebSocketClosed
+	}
+
+	op := byte(typ)
+	for {
+		chunk := data
+		if ws.fragmentSize > 0 && len(chunk) > ws.fragmentSize {
+			chunk = chunk[:ws.fragmentSize]
+		}
+		data = data[len(chunk):]
+
+		// RSV1 只在第一帧上设置
+		if err := ws.writeFrameLocked(len(data) == 0, compressed && op != opContinuation, op, chunk); err != nil {
+			return err
+		}
+
+		if len(data) == 0 {
+			return nil
+		}
+		op = opContinuation
+	}
+}
+
+// WriteText sends a text message.
+func (ws *WebSocket) WriteText(s string) error {
+	return ws.WriteMessage(TextMessage, []byte(s))
+}
+
+// WriteJSON sends v encoded as JSON in a text message.
+func (ws *WebSocket) WriteJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, b)
+}
+
+// ReadMessage reads the next data message, reassembling fragmented
+// messages and answering pings on the way. It returns a *CloseError when
+// the server closes the connection.
+func (ws *WebSocket) ReadMessage() (MessageType, []byte, error) {
+	ws.readMu.Lock()
+	defer ws.readMu.Unlock()
+
+	return ws.readMessage()
+}
+
+// ReadJSON reads the next data message and decodes it as JSON into v.
+func (ws *WebSocket) ReadJSON(v interface{}) error {
+	_, b, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Close closes the connection normally, see CloseWithReason.
+func (ws *WebSocket) Close() error {
+	return ws.CloseWithReason(CloseNormal, "")
+}
+
+// CloseWithReason performs the closing handshake: it sends a close frame
+// with code and reason, waits up to 5 seconds for the server to acknowledge
+// it and closes the connection. The messages received meanwhile are
+// discarded unless another goroutine is reading.
+func (ws *WebSocket) CloseWithReason(code int, reason string) error {
+	var payload []byte
+	if code != CloseNoStatus {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+
+	if len(payload) > 125 {
+		return errors.New("request: websocket close reason too long")
+	}
+
+	if err := ws.sendClose(payload); err != nil {
+		ws.conn.Close()
+		if err == ErrWebSocketClosed {
+			return nil
+		}
+		return err
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	if ws.readMu.TryLock() {
+		ws.conn.SetReadDeadline(deadline)
+		for {
+			if _, _, err := ws.readMessage(); err != nil {
+				break
+			}
+		}
+		ws.readMu.Unlock()
+	} else {
+		// 另一个 goroutine 正在读取，等它收到 close 帧
+		select {
+		case <-ws.gotClose:
+		case <-time.After(time.Until(deadline)):
+		}
+	}
+
+	return ws.conn.Close()
+}
+
+// sendClose sends a close frame unless one was already sent.
+func (ws *WebSocket) sendClose(payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	if ws.closing {
+		return ErrWebSocketClosed
+	}
+
+	ws.closing = true
+	return ws.writeFrameLocked(true, false, opClose, payload)
+}
+
+func (ws *WebSocket) writeFrame(fin, rsv1 bool, op byte, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	if ws.closing {
+		return ErrWebSocketClosed
+	}
+	return ws.writeFrameLocked(fin, rsv1, op, payload)
+}
+
+// writeFrameLocked writes a single masked frame, writeMu must be held.
+func (ws *WebSocket) writeFrameLocked(fin, rsv1 bool, op byte, payload []byte) error {
+	buf := make([]byte, 0, 14+len(payload))
+
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	buf = append(buf, b0)
+
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, 0x80|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 0x80|126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, 0x80|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+
+	var key [4]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	buf = append(buf, key[:]...)
+
+	start := len(buf)
+	buf = append(buf, payload...)
+	for i := range buf[start:] {
+		buf[start+i] ^= key[i&3]
+	}
+
+	_, err := ws.conn.Write(buf)
+	return err
+}
+
+type frameHeader struct {
+	fin    bool
+	rsv1   bool
+	op     byte
+	length int64
+}
+
+func (ws *WebSocket) readFrameHeader() (frameHeader, error) {
+	var h frameHeader
+
+	var b [8]byte
+	if _, err := io.ReadFull(ws.br, b[:2]); err != nil {
+		return h, err
+	}
+
+	h.fin = b[0]&0x80 != 0
+	h.rsv1 = b[0]&0x40 != 0
+	h.op = b[0] & 0x0f
+
+	if b[0]&0x30 != 0 || (h.rsv1 && !ws.compress) {
+		return h, ws.protocolError("unexpected reserved bits")
+	}
+
+	if b[1]&0x80 != 0 {
+		return h, ws.protocolError("masked frame from server")
+	}
+
+	switch n := b[1] & 0x7f; n {
+	case 126:
+		if _, err := io.ReadFull(ws.br, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(ws.br, b[:8]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint64(b[:8]))
+		if h.length < 0 {
+			return h, ws.protocolError("invalid frame length")
+		}
+	default:
+		h.length = int64(n)
+	}
+
+	if h.op >= opClose {
+		switch {
+		case h.op > opPong:
+			return h, ws.protocolError(fmt.Sprintf("unknown opcode %d", h.op))
+		case !h.fin || h.length > 125:
+			return h, ws.protocolError("invalid control frame")
+		case h.rsv1:
+			return h, ws.protocolError("compressed control frame")
+		}
+	} else if h.op > opBinary {
+		return h, ws.protocolError(fmt.Sprintf("unknown opcode %d", h.op))
+	}
+
+	return h, nil
+}
+
+// readMessage reads frames until a complete data message, readMu must be
+// held.
+func (ws *WebSocket) readMessage() (MessageType, []byte, error) {
+	var (
+		msg        []byte
+		typ        byte
+		compressed bool
+	)
+
+	for {
+		h, err := ws.readFrameHeader()
+		if err != nil {
+			return 0, nil, ws.readError(err)
+		}
+
+		if h.op < opClose && int64(len(msg))+h.length > ws.readLimit {
+			ws.fail(CloseMessageTooBig, "message too big")
+			return 0, nil, fmt.Errorf("request: websocket message larger than %d bytes", ws.readLimit)
+		}
+
+		payload := make([]byte, h.length)
+		if _, err := io.ReadFull(ws.br, payload); err != nil {
+			return 0, nil, ws.readError(err)
+		}
+
+		switch h.op {
+		case opPing:
+			if err := ws.writeFrame(true, false, opPong, payload); err != nil && err != ErrWebSocketClosed {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			ws.mu.Lock()
+			fn := ws.onPong
+			ws.mu.Unlock()
+			if fn != nil {
+				fn(payload)
+			}
+			continue
+		case opClose:
+			return 0, nil, ws.handleClose(payload)
+		case opContinuation:
+			if typ == 0 {
+				return 0, nil, ws.protocolError("unexpected continuation frame")
+			}
+		default:
+			if typ != 0 {
+				return 0, nil, ws.protocolError("interleaved data frames")
+			}
+			typ, compressed = h.op, h.rsv1
+		}
+
+		msg = append(msg, payload...)
+
+		if !h.fin {
+			continue
+		}
+
+		if compressed {
+			if msg, err = inflateMessage(msg, ws.readLimit); err != nil {
+				ws.fail(CloseInvalidPayload, "invalid compressed message")
+				return 0, nil, err
+			}
+		}
+
+		if typ == opText && !utf8.Valid(msg) {
+			ws.fail(CloseInvalidPayload, "invalid UTF-8")
+			return 0, nil, errors.New("request: websocket text message is not valid UTF-8")
+		}
+
+		return MessageType(typ), msg, nil
+	}
+}
+
+// handleClose acknowledges the close frame of the server.
+func (ws *WebSocket) handleClose(payload []byte) error {
+	ce := &CloseError{Code: CloseNoStatus}
+
+	switch {
+	case len(payload) == 1:
+		ce.Code = CloseProtocolError
+	case len(payload) >= 2:
+		ce.Code = int(binary.BigEndian.Uint16(payload))
+		ce.Reason = string(payload[2:])
+		if !utf8.ValidString(ce.Reason) {
+			ce.Code = CloseProtocolError
+		}
+	}
+
+	// 回应收到的状态码，无效的 close 帧回应 1002
+	var ack []byte
+	if ce.Code != CloseNoStatus {
+		ack = binary.BigEndian.AppendUint16(nil, uint16(ce.Code))
+	}
+	ws.sendClose(ack)
+
+	ws.mu.Lock()
+	if ws.closeErr == nil {
+		ws.closeErr = ce
+		close(ws.gotClose)
+	}
+	ws.mu.Unlock()
+
+	return ce
+}
+
+// readError returns the close error once the connection was closed by the
+// server.
+func (ws *WebSocket) readError(err error) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.closeErr != nil {
+		return ws.closeErr
+	}
+
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (ws *WebSocket) protocolError(reason string) error {
+	ws.fail(CloseProtocolError, reason)
+	return errors.New("request: websocket protocol error: " + reason)
+}
+
+// fail sends a close frame with code and closes the connection.
+func (ws *WebSocket) fail(code int, reason string) {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	ws.sendClose(append(payload, reason...))
+	ws.conn.Close()
+}
+
+// deflateMessage compresses a message as defined by RFC 7692, without
+// context takeover.
+func deflateMessage(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+
+	// 去掉 sync flush 末尾的 00 00 ff ff
+	return bytes.TrimSuffix(buf.Bytes(), []byte{0x00, 0x00, 0xff, 0xff}), nil
+}
+
+// inflateMessage decompresses a message compressed without context
+// takeover, up to limit bytes.
+func inflateMessage(data []byte, limit int64) ([]byte, error) {
+	tail := []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+	fr := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(tail)))
+	defer fr.Close()
+
+	b, err := io.ReadAll(io.LimitReader(fr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("request: websocket message larger than %d bytes", limit)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,450 @@
+package httpclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebSocketOptions configures the WebSocket handshake and connection.
+type WebSocketOptions struct {
+	// Subprotocols are offered in Sec-WebSocket-Protocol, in order of
+	// preference.
+	Subprotocols []string
+
+	// Compression offers the permessage-deflate extension, messages are
+	// then compressed when the server accepts it.
+	Compression bool
+
+	// ReadLimit is the maximum size of a received message, it defaults to
+	// 32 MiB.
+	ReadLimit int64
+
+	// FragmentSize splits the written messages into frames of at most
+	// FragmentSize bytes, 0 writes every message as a single frame.
+	FragmentSize int
+}
+
+// ErrBadHandshake is returned when the server does not upgrade the
+// connection, the response is available in WebSocketHandshakeError.
+var ErrBadHandshake = errors.New("request: bad websocket handshake")
+
+// WebSocketHandshakeError is returned when the server answers the handshake
+// with something else than a valid 101 Switching Protocols response.
+type WebSocketHandshakeError struct {
+	Response *http.Response
+	Reason   string
+}
+
+func (e *WebSocketHandshakeError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrBadHandshake, e.Reason)
+}
+
+// Unwrap returns ErrBadHandshake.
+func (e *WebSocketHandshakeError) Unwrap() error {
+	return ErrBadHandshake
+}
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opens a WebSocket connection to the URL of the request, which
+// may use the ws, wss, http or https scheme. The connection reuses the
+// configuration of the request and its session: headers, cookies, basic
+// authentication, the user agent, the context and the total timeout, which
+// limits the handshake. It is dialed like the HTTP requests, through the
+// proxy and with the TLS configuration of the session transport or of
+// Settings().ProxyTransport.
+func (c *Client) WebSocket(opts WebSocketOptions) (*WebSocket, error) {
+	c.method = http.MethodGet
+
+	if _, err := c.Req(); err != nil {
+		return nil, err
+	}
+
+	req := c.req
+	secure := false
+
+	switch req.URL.Scheme {
+	case "ws", "http":
+	case "wss", "https":
+		secure = true
+	default:
+		return nil, fmt.Errorf("request: unsupported websocket scheme %q", req.URL.Scheme)
+	}
+
+	ctx := req.Context()
+	if total := c.resolvedTimeouts().Total; total > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, total)
+		defer cancel()
+	}
+
+	transport := websocketTransport(c.cli.Transport)
+
+	conn, err := dialWebSocket(ctx, transport, req.URL, secure)
+	if err != nil {
+		return nil, err
+	}
+
+	// 握手期间遵守 ctx 的截止时间和取消
+	stop, stopped := make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+
+	ws, err := handshake(conn, req, opts)
+	close(stop)
+	<-stopped
+
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return ws, nil
+}
+
+func websocketTransport(rt http.RoundTripper) *http.Transport {
+	if h, ok := rt.(*harTransport); ok {
+		rt = h.rt
+	}
+
+	if t, ok := rt.(*http.Transport); ok {
+		return t
+	}
+
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		return t
+	}
+
+	return &http.Transport{}
+}
+
+func hostPort(u *url.URL, secure bool) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+
+	if secure {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// dialWebSocket opens the connection to u, through the proxy of t if any,
+// and performs the TLS handshake for secure URLs.
+func dialWebSocket(ctx context.Context, t *http.Transport, u *url.URL, secure bool) (net.Conn, error) {
+	addr := hostPort(u, secure)
+
+	// WebSocket 连接不计入连接池统计
+	dialContext, dialFn := t.DialContext, t.Dial
+	if ct := trackerOf(t); ct != nil {
+		dialContext, dialFn = ct.dialContext, ct.dial
+	}
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		switch {
+		case dialContext != nil:
+			return dialContext(ctx, network, addr)
+		case dialFn != nil:
+			return dialFn(network, addr)
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+
+	var proxyURL *url.URL
+	if t.Proxy != nil {
+		// Proxy 按 HTTP 的 scheme 选择代理
+		pu := *u
+		pu.Scheme = "http"
+		if secure {
+			pu.Scheme = "https"
+		}
+
+		var err error
+		if proxyURL, err = t.Proxy(&http.Request{URL: &pu, Header: make(http.Header)}); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	switch {
+	case proxyURL == nil:
+		conn, err = dial(ctx, "tcp", addr)
+	case proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h":
+		conn, err = dialSOCKS(ctx, dial, proxyURL, addr)
+	default:
+		conn, err = dialTunnel(ctx, t, dial, proxyURL, addr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !secure {
+		return conn, nil
+	}
+
+	cfg := &tls.Config{}
+	if t.TLSClientConfig != nil {
+		cfg = t.TLSClientConfig.Clone()
+	}
+
+	if cfg.ServerName == "" {
+		cfg.ServerName = u.Hostname()
+	}
+
+	// WebSocket 只能在 HTTP/1.1 上升级
+	cfg.NextProtos = []string{"http/1.1"}
+
+	tc := tls.Client(conn, cfg)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tc, nil
+}
+
+// dialSOCKS opens a connection to addr through a SOCKS5 proxy, which is
+// reached with dial.
+func dialSOCKS(ctx context.Context, dial func(context.Context, string, string) (net.Conn, error), proxyURL *url.URL, addr string) (net.Conn, error) {
+	forward := proxyDialer{DialerFunc(func(_ context.Context, network, addr string) (net.Conn, error) {
+		return dial(ctx, network, addr)
+	})}
+
+	d, err := socksDialer(proxyURL, forward)
+	if err != nil {
+		return nil, err
+	}
+
+	// SOCKS5 握手也要遵守 ctx
+	if cd, ok := d.(Dialer); ok {
+		return cd.DialContext(ctx, "tcp", addr)
+	}
+	return d.Dial("tcp", addr)
+}
+
+// dialTunnel opens a CONNECT tunnel to addr through an HTTP proxy.
+func dialTunnel(ctx context.Context, t *http.Transport, dial func(context.Context, string, string) (net.Conn, error), proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := dial(ctx, "tcp", hostPort(proxyURL, proxyURL.Scheme == "https"))
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL.Scheme == "https" {
+		cfg := &tls.Config{ServerName: proxyURL.Hostname()}
+		if t.TLSClientConfig != nil {
+			cfg = t.TLSClientConfig.Clone()
+			cfg.ServerName = proxyURL.Hostname()
+		}
+
+		tc := tls.Client(conn, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+
+	connect := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		connect.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	if err := connect.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, connect)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("request: proxy refused the tunnel: %s", res.Status)
+	}
+
+	if br.Buffered() > 0 {
+		conn.Close()
+		return nil, errors.New("request: proxy sent data before the tunnel was established")
+	}
+
+	return conn, nil
+}
+
+// handshake performs the opening handshake of RFC 6455 on conn.
+func handshake(conn net.Conn, req *http.Request, opts WebSocketOptions) (*WebSocket, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	hs := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: req.URL.Path, RawPath: req.URL.RawPath, RawQuery: req.URL.RawQuery},
+		Host:       req.URL.Host,
+		Header:     req.Header.Clone(),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+
+	if hs.URL.Path == "" {
+		hs.URL.Path = "/"
+	}
+
+	hs.Header.Set("Upgrade", "websocket")
+	hs.Header.Set("Connection", "Upgrade")
+	hs.Header.Set("Sec-WebSocket-Key", key)
+	hs.Header.Set("Sec-WebSocket-Version", "13")
+	hs.Header.Del("Content-Type")
+
+	if len(opts.Subprotocols) > 0 {
+		hs.Header.Set("Sec-WebSocket-Protocol", strings.Join(opts.Subprotocols, ", "))
+	}
+
+	if opts.Compression {
+		hs.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate; server_no_context_takeover; client_no_context_takeover")
+	}
+
+	bw := bufio.NewWriter(conn)
+	if err := hs.Write(bw); err != nil {
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, hs)
+	if err != nil {
+		return nil, err
+	}
+
+	fail := func(reason string) (*WebSocket, error) {
+		return nil, &WebSocketHandshakeError{Response: res, Reason: reason}
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		return fail("unexpected status " + res.Status)
+	}
+
+	if !strings.EqualFold(res.Header.Get("Upgrade"), "websocket") || !headerContainsToken(res.Header, "Connection", "upgrade") {
+		return fail("missing upgrade headers")
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	if res.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return fail("invalid Sec-WebSocket-Accept")
+	}
+
+	protocol := res.Header.Get("Sec-WebSocket-Protocol")
+	if protocol != "" && !containsString(opts.Subprotocols, protocol) {
+		return fail("server selected an unoffered subprotocol " + protocol)
+	}
+
+	compress := false
+	for _, ext := range res.Header.Values("Sec-WebSocket-Extensions") {
+		for _, e := range strings.Split(ext, ",") {
+			params := strings.Split(e, ";")
+			if strings.TrimSpace(params[0]) != "permessage-deflate" {
+				return fail("server selected an unoffered extension " + strings.TrimSpace(params[0]))
+			}
+
+			if !opts.Compression {
+				return fail("server selected permessage-deflate which was not offered")
+			}
+
+			compress = true
+			takeover := true
+			for _, p := range params[1:] {
+				if strings.TrimSpace(p) == "server_no_context_takeover" {
+					takeover = false
+				}
+			}
+
+			if takeover {
+				return fail("server kept the compression context")
+			}
+		}
+	}
+
+	readLimit := opts.ReadLimit
+	if readLimit <= 0 {
+		readLimit = 32 << 20
+	}
+
+	return &WebSocket{
+		conn:         conn,
+		br:           br,
+		protocol:     protocol,
+		compress:     compress,
+		readLimit:    readLimit,
+		fragmentSize: opts.FragmentSize,
+		gotClose:     make(chan struct{}),
+	}, nil
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,695 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frameConn is a net.Conn reading the frames of the server from a buffer
+// and keeping the frames written by the client.
+type frameConn struct {
+	r      *bytes.Reader
+	w      bytes.Buffer
+	closed bool
+}
+
+func (c *frameConn) Read(p []byte) (int, error)         { return c.r.Read(p) }
+func (c *frameConn) Write(p []byte) (int, error)        { return c.w.Write(p) }
+func (c *frameConn) Close() error                       { c.closed = true; return nil }
+func (c *frameConn) LocalAddr() net.Addr                { return nil }
+func (c *frameConn) RemoteAddr() net.Addr               { return nil }
+func (c *frameConn) SetDeadline(t time.Time) error      { return nil }
+func (c *frameConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *frameConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func newTestWebSocket(input []byte, compress bool, readLimit int64, fragmentSize int) (*WebSocket, *frameConn) {
+	conn := &frameConn{r: bytes.NewReader(input)}
+	if readLimit == 0 {
+		readLimit = 32 << 20
+	}
+
+	return &WebSocket{
+		conn:         conn,
+		br:           bufio.NewReader(conn),
+		compress:     compress,
+		readLimit:    readLimit,
+		fragmentSize: fragmentSize,
+		gotClose:     make(chan struct{}),
+	}, conn
+}
+
+// frame encodes an unmasked frame, as sent by a server.
+func frame(fin, rsv1 bool, op byte, payload []byte) []byte {
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+
+	b := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		b = append(b, byte(n))
+	case n <= 0xffff:
+		b = append(b, 126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	return append(b, payload...)
+}
+
+func frames(f ...[]byte) []byte {
+	return bytes.Join(f, nil)
+}
+
+func closePayload(code int, reason string) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return append(b, reason...)
+}
+
+type clientFrame struct {
+	fin     bool
+	rsv1    bool
+	op      byte
+	payload []byte
+}
+
+// readClientFrames decodes the frames written by the client, which must
+// all be masked.
+func readClientFrames(t *testing.T, b []byte) []clientFrame {
+	t.Helper()
+
+	var out []clientFrame
+	for len(b) > 0 {
+		if len(b) < 2 || b[1]&0x80 == 0 {
+			t.Fatalf("client frame not masked: % x", b)
+		}
+
+		f := clientFrame{fin: b[0]&0x80 != 0, rsv1: b[0]&0x40 != 0, op: b[0] & 0x0f}
+		n, off := int(b[1]&0x7f), 2
+		switch n {
+		case 126:
+			n, off = int(binary.BigEndian.Uint16(b[2:])), 4
+		case 127:
+			n, off = int(binary.BigEndian.Uint64(b[2:])), 10
+		}
+
+		key := b[off : off+4]
+		off += 4
+		f.payload = make([]byte, n)
+		for i := range f.payload {
+			f.payload[i] = b[off+i] ^ key[i&3]
+		}
+
+		out = append(out, f)
+		b = b[off+n:]
+	}
+	return out
+}
+
+func TestWebSocketWriteMessage(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), 70000)
+	medium := bytes.Repeat([]byte("b"), 200)
+
+	tests := []struct {
+		name         string
+		typ          MessageType
+		data         []byte
+		fragmentSize int
+		want         []clientFrame
+	}{
+		{
+			name: "text",
+			typ:  TextMessage,
+			data: []byte("hi"),
+			want: []clientFrame{{fin: true, op: opText, payload: []byte("hi")}},
+		},
+		{
+			name: "empty",
+			typ:  BinaryMessage,
+			data: nil,
+			want: []clientFrame{{fin: true, op: opBinary, payload: []byte{}}},
+		},
+		{
+			name: "16-bit length",
+			typ:  BinaryMessage,
+			data: medium,
+			want: []clientFrame{{fin: true, op: opBinary, payload: medium}},
+		},
+		{
+			name: "64-bit length",
+			typ:  BinaryMessage,
+			data: long,
+			want: []clientFrame{{fin: true, op: opBinary, payload: long}},
+		},
+		{
+			name:         "fragmented",
+			typ:          TextMessage,
+			data:         []byte("hello world"),
+			fragmentSize: 4,
+			want: []clientFrame{
+				{op: opText, payload: []byte("hell")},
+				{op: opContinuation, payload: []byte("o wo")},
+				{fin: true, op: opContinuation, payload: []byte("rld")},
+			},
+		},
+		{
+			name:         "exact fragments",
+			typ:          BinaryMessage,
+			data:         []byte("abcd"),
+			fragmentSize: 2,
+			want: []clientFrame{
+				{op: opBinary, payload: []byte("ab")},
+				{fin: true, op: opContinuation, payload: []byte("cd")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		ws, conn := newTestWebSocket(nil, false, 0, tt.fragmentSize)
+		if err := ws.WriteMessage(tt.typ, tt.data); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		got := readClientFrames(t, conn.w.Bytes())
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: %d frames written, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+
+		for i, f := range got {
+			w := tt.want[i]
+			if f.fin != w.fin || f.rsv1 || f.op != w.op || !bytes.Equal(f.payload, w.payload) {
+				t.Errorf("%s: frame %d = fin %v op %d %d bytes, want fin %v op %d %d bytes",
+					tt.name, i, f.fin, f.op, len(f.payload), w.fin, w.op, len(w.payload))
+			}
+		}
+	}
+}
+
+func TestWebSocketWriteCompressed(t *testing.T) {
+	data := []byte(strings.Repeat("compressible message ", 50))
+
+	ws, conn := newTestWebSocket(nil, true, 0, 16)
+	if err := ws.WriteMessage(TextMessage, data); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload []byte
+	got := readClientFrames(t, conn.w.Bytes())
+	for i, f := range got {
+		// RSV1 只在第一帧上
+		if f.rsv1 != (i == 0) {
+			t.Errorf("frame %d: rsv1 = %v", i, f.rsv1)
+		}
+		payload = append(payload, f.payload...)
+	}
+
+	if len(got) < 2 || !got[len(got)-1].fin {
+		t.Fatalf("%d frames written, want a fragmented message", len(got))
+	}
+
+	msg, err := inflateMessage(payload, 1<<20)
+	if err != nil || !bytes.Equal(msg, data) {
+		t.Errorf("inflated message = %q, %v, want %q", msg, err, data)
+	}
+}
+
+func TestWebSocketWriteErrors(t *testing.T) {
+	ws, conn := newTestWebSocket(nil, false, 0, 0)
+
+	if err := ws.WriteMessage(MessageType(opClose), nil); err == nil {
+		t.Error("WriteMessage with a control opcode succeeded")
+	}
+
+	if err := ws.Ping(make([]byte, 126)); err == nil {
+		t.Error("Ping with 126 bytes succeeded")
+	}
+
+	if err := ws.CloseWithReason(CloseNormal, strings.Repeat("x", 124)); err == nil {
+		t.Error("CloseWithReason with a 126 bytes payload succeeded")
+	}
+
+	if conn.w.Len() != 0 {
+		t.Errorf("frames written after invalid calls: % x", conn.w.Bytes())
+	}
+
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ws.WriteText("late"); err != ErrWebSocketClosed {
+		t.Errorf("WriteText after Close = %v, want %v", err, ErrWebSocketClosed)
+	}
+
+	got := readClientFrames(t, conn.w.Bytes())
+	if len(got) != 1 || got[0].op != opClose || !bytes.Equal(got[0].payload, closePayload(CloseNormal, "")) {
+		t.Errorf("frames written by Close = %+v, want a single close frame", got)
+	}
+	if !conn.closed {
+		t.Error("connection not closed")
+	}
+}
+
+func TestWebSocketReadMessage(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), 70000)
+	medium := bytes.Repeat([]byte("b"), 200)
+
+	compressed, err := deflateMessage([]byte("hello hello hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hugeLength := []byte{0x82, 127, 0x80, 0, 0, 0, 0, 0, 0, 0}
+
+	tests := []struct {
+		name      string
+		input     []byte
+		compress  bool
+		readLimit int64
+		typ       MessageType
+		want      []byte
+		err       string // 错误信息的子串
+		closeErr  *CloseError
+		replies   []clientFrame // 客户端写出的帧
+	}{
+		{
+			name:  "text",
+			input: frame(true, false, opText, []byte("hello")),
+			typ:   TextMessage,
+			want:  []byte("hello"),
+		},
+		{
+			name:  "16-bit length",
+			input: frame(true, false, opBinary, medium),
+			typ:   BinaryMessage,
+			want:  medium,
+		},
+		{
+			name:  "64-bit length",
+			input: frame(true, false, opBinary, long),
+			typ:   BinaryMessage,
+			want:  long,
+		},
+		{
+			name:  "empty",
+			input: frame(true, false, opBinary, nil),
+			typ:   BinaryMessage,
+			want:  []byte{},
+		},
+		{
+			name: "fragmented with ping",
+			input: frames(
+				frame(false, false, opText, []byte("Hel")),
+				frame(true, false, opPing, []byte("p")),
+				frame(false, false, opContinuation, []byte("lo ")),
+				frame(true, false, opContinuation, []byte("world")),
+			),
+			typ:     TextMessage,
+			want:    []byte("Hello world"),
+			replies: []clientFrame{{fin: true, op: opPong, payload: []byte("p")}},
+		},
+		{
+			name:     "compressed",
+			input:    frame(true, true, opText, compressed),
+			compress: true,
+			typ:      TextMessage,
+			want:     []byte("hello hello hello"),
+		},
+		{
+			name: "compressed fragments",
+			input: frames(
+				frame(false, true, opBinary, compressed[:3]),
+				frame(true, false, opContinuation, compressed[3:]),
+			),
+			compress: true,
+			typ:      BinaryMessage,
+			want:     []byte("hello hello hello"),
+		},
+		{
+			name:    "compressed without extension",
+			input:   frame(true, true, opText, compressed),
+			err:     "unexpected reserved bits",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "unexpected reserved bits")}},
+		},
+		{
+			name:    "rsv2",
+			input:   []byte{0xa1, 0},
+			err:     "unexpected reserved bits",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "unexpected reserved bits")}},
+		},
+		{
+			name:    "masked",
+			input:   []byte{0x81, 0x81, 1, 2, 3, 4, 'a'},
+			err:     "masked frame from server",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "masked frame from server")}},
+		},
+		{
+			name:    "unknown data opcode",
+			input:   frame(true, false, 0x3, nil),
+			err:     "unknown opcode 3",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "unknown opcode 3")}},
+		},
+		{
+			name:    "unknown control opcode",
+			input:   frame(true, false, 0xb, nil),
+			err:     "unknown opcode 11",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "unknown opcode 11")}},
+		},
+		{
+			name:    "fragmented control frame",
+			input:   frame(false, false, opPing, nil),
+			err:     "invalid control frame",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "invalid control frame")}},
+		},
+		{
+			name:    "long control frame",
+			input:   frame(true, false, opPing, medium),
+			err:     "invalid control frame",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "invalid control frame")}},
+		},
+		{
+			name:     "compressed control frame",
+			input:    frame(true, true, opPing, nil),
+			compress: true,
+			err:      "compressed control frame",
+			replies:  []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "compressed control frame")}},
+		},
+		{
+			name:    "unexpected continuation",
+			input:   frame(true, false, opContinuation, []byte("x")),
+			err:     "unexpected continuation frame",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "unexpected continuation frame")}},
+		},
+		{
+			name: "interleaved data frames",
+			input: frames(
+				frame(false, false, opText, []byte("a")),
+				frame(true, false, opBinary, []byte("b")),
+			),
+			err:     "interleaved data frames",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "interleaved data frames")}},
+		},
+		{
+			name:    "invalid length",
+			input:   hugeLength,
+			err:     "invalid frame length",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "invalid frame length")}},
+		},
+		{
+			name:    "invalid utf-8",
+			input:   frame(true, false, opText, []byte{0xff, 0xfe}),
+			err:     "not valid UTF-8",
+			replies: []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseInvalidPayload, "invalid UTF-8")}},
+		},
+		{
+			name:      "too big",
+			input:     frame(true, false, opBinary, medium),
+			readLimit: 100,
+			err:       "larger than 100 bytes",
+			replies:   []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseMessageTooBig, "message too big")}},
+		},
+		{
+			name: "too big across fragments",
+			input: frames(
+				frame(false, false, opBinary, medium[:60]),
+				frame(true, false, opContinuation, medium[:60]),
+			),
+			readLimit: 100,
+			err:       "larger than 100 bytes",
+			replies:   []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseMessageTooBig, "message too big")}},
+		},
+		{
+			name:     "close",
+			input:    frame(true, false, opClose, closePayload(CloseGoingAway, "bye")),
+			closeErr: &CloseError{Code: CloseGoingAway, Reason: "bye"},
+			replies:  []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseGoingAway, "")}},
+		},
+		{
+			name:     "close without status",
+			input:    frame(true, false, opClose, nil),
+			closeErr: &CloseError{Code: CloseNoStatus},
+			replies:  []clientFrame{{fin: true, op: opClose, payload: []byte{}}},
+		},
+		{
+			name:     "close with one byte",
+			input:    frame(true, false, opClose, []byte{3}),
+			closeErr: &CloseError{Code: CloseProtocolError},
+			replies:  []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "")}},
+		},
+		{
+			name:     "close with invalid reason",
+			input:    frame(true, false, opClose, closePayload(CloseNormal, "\xff")),
+			closeErr: &CloseError{Code: CloseProtocolError, Reason: "\xff"},
+			replies:  []clientFrame{{fin: true, op: opClose, payload: closePayload(CloseProtocolError, "")}},
+		},
+		{
+			name:  "truncated payload",
+			input: frame(true, false, opText, []byte("hello"))[:4],
+			err:   io.ErrUnexpectedEOF.Error(),
+		},
+		{
+			name:  "truncated length",
+			input: []byte{0x82, 126, 1},
+			err:   io.ErrUnexpectedEOF.Error(),
+		},
+		{
+			name:  "end of stream",
+			input: nil,
+			err:   io.ErrUnexpectedEOF.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		ws, conn := newTestWebSocket(tt.input, tt.compress, tt.readLimit, 0)
+		typ, msg, err := ws.ReadMessage()
+
+		switch {
+		case tt.closeErr != nil:
+			var ce *CloseError
+			if !errors.As(err, &ce) || *ce != *tt.closeErr {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.closeErr)
+			}
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case typ != tt.typ || !bytes.Equal(msg, tt.want):
+			t.Errorf("%s: message = %d %q, want %d %q", tt.name, typ, msg, tt.typ, tt.want)
+		}
+
+		got := readClientFrames(t, conn.w.Bytes())
+		if len(got) != len(tt.replies) {
+			t.Errorf("%s: %d frames written, want %d", tt.name, len(got), len(tt.replies))
+			continue
+		}
+		for i, f := range got {
+			w := tt.replies[i]
+			if f.fin != w.fin || f.op != w.op || !bytes.Equal(f.payload, w.payload) {
+				t.Errorf("%s: frame %d = %+v, want %+v", tt.name, i, f, w)
+			}
+		}
+	}
+}
+
+func TestWebSocketPong(t *testing.T) {
+	input := frames(
+		frame(true, false, opPong, []byte("1")),
+		frame(true, false, opPong, []byte("2")),
+		frame(true, false, opText, []byte("done")),
+	)
+
+	ws, _ := newTestWebSocket(input, false, 0, 0)
+
+	var pongs []string
+	ws.OnPong(func(data []byte) { pongs = append(pongs, string(data)) })
+
+	if _, msg, err := ws.ReadMessage(); err != nil || string(msg) != "done" {
+		t.Fatalf("ReadMessage = %q, %v", msg, err)
+	}
+
+	if strings.Join(pongs, ",") != "1,2" {
+		t.Errorf("pongs = %q, want [1 2]", pongs)
+	}
+}
+
+func TestWebSocketCloseHandshake(t *testing.T) {
+	input := frames(
+		frame(true, false, opText, []byte("discarded")),
+		frame(true, false, opClose, closePayload(CloseNormal, "")),
+	)
+
+	ws, conn := newTestWebSocket(input, false, 0, 0)
+	if err := ws.CloseWithReason(CloseGoingAway, "shutdown"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readClientFrames(t, conn.w.Bytes())
+	if len(got) != 1 || got[0].op != opClose || !bytes.Equal(got[0].payload, closePayload(CloseGoingAway, "shutdown")) {
+		t.Errorf("frames written = %+v, want a single close frame", got)
+	}
+
+	if conn.r.Len() != 0 {
+		t.Errorf("%d bytes of the close acknowledgement not read", conn.r.Len())
+	}
+
+	if !conn.closed {
+		t.Error("connection not closed")
+	}
+
+	if err := ws.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
+
+func TestInflateMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		limit int64
+		err   bool
+	}{
+		{"empty", nil, 10, false},
+		{"short", []byte("hello"), 10, false},
+		{"at limit", bytes.Repeat([]byte("x"), 10), 10, false},
+		{"over limit", bytes.Repeat([]byte("x"), 11), 10, true},
+		{"large", bytes.Repeat([]byte("0123456789"), 10000), 1 << 20, false},
+	}
+
+	for _, tt := range tests {
+		compressed, err := deflateMessage(tt.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		got, err := inflateMessage(compressed, tt.limit)
+		switch {
+		case tt.err && err == nil:
+			t.Errorf("%s: inflateMessage succeeded, want an error", tt.name)
+		case !tt.err && (err != nil || !bytes.Equal(got, tt.data)):
+			t.Errorf("%s: inflateMessage = %d bytes, %v, want %d bytes", tt.name, len(got), err, len(tt.data))
+		}
+	}
+
+	if _, err := inflateMessage([]byte{0xff, 0xff, 0xff}, 10); err == nil {
+		t.Error("inflateMessage of invalid data succeeded")
+	}
+}
+
+// serveSOCKS5 answers the handshake of a SOCKS5 client without
+// authentication and returns the address it asks to connect to.
+func serveSOCKS5(conn net.Conn) (string, error) {
+	r := bufio.NewReader(conn)
+
+	greeting := make([]byte, 2)
+	if _, err := io.ReadFull(r, greeting); err != nil {
+		return "", err
+	}
+	if _, err := io.ReadFull(r, make([]byte, greeting[1])); err != nil {
+		return "", err
+	}
+	if _, err := conn.Write([]byte{5, 0}); err != nil {
+		return "", err
+	}
+
+	// VER CMD RSV ATYP=域名 LEN
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return "", err
+	}
+	host := make([]byte, int(head[4])+2)
+	if _, err := io.ReadFull(r, host); err != nil {
+		return "", err
+	}
+
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		return "", err
+	}
+
+	port := int(host[len(host)-2])<<8 | int(host[len(host)-1])
+	return net.JoinHostPort(string(host[:len(host)-2]), strconv.Itoa(port)), nil
+}
+
+func TestDialWebSocketSOCKS5(t *testing.T) {
+	for _, scheme := range []string{"socks5", "socks5h"} {
+		var dialed string
+		target := make(chan string, 1)
+
+		proxyURL := &url.URL{Scheme: scheme, Host: "proxy.test:1080"}
+		tr := &http.Transport{
+			Proxy: http.ProxyURL(proxyURL),
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dialed = addr
+				client, server := net.Pipe()
+				go func() {
+					addr, err := serveSOCKS5(server)
+					if err != nil {
+						addr = err.Error()
+					}
+					target <- addr
+				}()
+				return client, nil
+			},
+		}
+
+		conn, err := dialWebSocket(context.Background(), tr, &url.URL{Scheme: "ws", Host: "example.test"}, false)
+		if err != nil {
+			t.Fatalf("%s: %v", scheme, err)
+		}
+		conn.Close()
+
+		if dialed != "proxy.test:1080" {
+			t.Errorf("%s: dialed %q, want the proxy", scheme, dialed)
+		}
+		if got := <-target; got != "example.test:80" {
+			t.Errorf("%s: proxy asked to connect to %q, want example.test:80", scheme, got)
+		}
+	}
+}
+
+func TestDialWebSocketSOCKS5Canceled(t *testing.T) {
+	tr := &http.Transport{
+		Proxy: http.ProxyURL(&url.URL{Scheme: "socks5", Host: "proxy.test:1080"}),
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			// 代理不响应握手
+			client, _ := net.Pipe()
+			return client, nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := dialWebSocket(ctx, tr, &url.URL{Scheme: "ws", Host: "example.test"}, false)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("dialWebSocket succeeded without a proxy answer")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dialWebSocket ignored the context during the SOCKS5 handshake")
+	}
+}